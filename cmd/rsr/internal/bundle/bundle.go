@@ -1,24 +1,37 @@
 package bundle
 
 import (
+	"context"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
 	"github.com/reposaur/reposaur/pkg/sdk"
+	"github.com/reposaur/reposaur/provider/gitea"
+	"github.com/reposaur/reposaur/provider/github"
+	"github.com/reposaur/reposaur/provider/gitlab"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 )
 
 type bundleParams struct {
-	policyPaths    []string
+	policyPaths  []string
 	experimental bool
+	signingKey   string
+	signingAlg   string
+	signingKeyID string
+	revision     string
+	minVersion   string
+	optimize     int
 }
 
 func NewCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "bundle [-p POLICY_PATH...] <OUTPUT>",
-		Short: "Creates a bundle from the policies at POLICY_PATH (experimental)",
-		Long:  "Creates a bundle from the policies at POLICY_PATH (experimental)",
+		Short: "Creates a bundle from the policies at POLICY_PATH",
+		Long:  "Creates a bundle from the policies at POLICY_PATH",
 	}
 
 	var (
@@ -27,7 +40,13 @@ func NewCmd() *cobra.Command {
 	)
 
 	cmdutil.AddPolicyPathsFlag(flags, &params.policyPaths)
+	cmdutil.AddSigningFlags(flags, &params.signingKey, &params.signingAlg, &params.signingKeyID)
+	cmdutil.AddManifestFlags(flags, &params.revision, &params.minVersion, &params.optimize)
+
 	cmdutil.AddExperimentalFlag(flags, &params.experimental)
+	_ = flags.MarkDeprecated("experimental", "bundles are no longer experimental")
+
+	cmd.AddCommand(newPushCmd())
 
 	cmd.Run = func(cmd *cobra.Command, args []string) {
 		var (
@@ -35,31 +54,71 @@ func NewCmd() *cobra.Command {
 			logger = zerolog.Ctx(ctx)
 		)
 
-		if !params.experimental {
-			logger.Fatal().Msg("experimental feature, please use --experimental to accept")
-		}
-
 		if len(args) != 1 {
 			logger.Fatal().Msgf("exactly 1 arguments required, got %d", len(args))
 		}
 
-		rsr, err := sdk.New(ctx, params.policyPaths, sdk.WithLogger(*logger))
+		// Providers don't need clients to build bundles, but their
+		// builtins must be registered for the policies to compile.
+		rsr, err := sdk.New(
+			ctx,
+			params.policyPaths,
+			sdk.WithLogger(*logger),
+			sdk.WithProvider(github.NewProvider(nil)),
+			sdk.WithProvider(gitlab.NewProvider(nil)),
+			sdk.WithProvider(gitea.NewProvider(nil)),
+		)
 		if err != nil {
 			logger.Fatal().Err(err).Msg("could not instantiate SDK")
 		}
 
-		out, err := os.OpenFile(args[0], os.O_WRONLY+os.O_CREATE+os.O_TRUNC, 0o666)
+		if params.revision == "" && len(params.policyPaths) > 0 {
+			params.revision = gitRevision(ctx, params.policyPaths[0])
+		}
+
+		out, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
 		if err != nil {
 			logger.Fatal().Err(err).Msg("could not open file")
 		}
+		defer out.Close()
 
-		err = rsr.Bundle(ctx, params.policyPaths, out)
+		bundleOpts := []sdk.BundleOption{
+			sdk.WithRevision(params.revision),
+			sdk.WithMinVersion(params.minVersion),
+			sdk.WithOptimization(params.optimize),
+		}
+
+		if params.signingKey != "" {
+			bundleOpts = append(bundleOpts, sdk.WithSigningKey(params.signingKey, params.signingAlg, params.signingKeyID))
+		}
+
+		err = rsr.Bundle(ctx, out, bundleOpts...)
 		if err != nil {
 			logger.Fatal().Err(err).Msg("could not create bundle")
 		}
 
-		logger.Info().Msgf("bundle written to %s", args[0])
+		if err := out.Close(); err != nil {
+			logger.Fatal().Err(err).Msg("could not write bundle")
+		}
+
+		logger.Info().Str("revision", params.revision).Msgf("bundle written to %s", args[0])
 	}
 
 	return cmd
 }
+
+// gitRevision returns the commit checked out in the git repository
+// that contains path, or an empty string if there's none.
+func gitRevision(ctx context.Context, path string) string {
+	dir := path
+	if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
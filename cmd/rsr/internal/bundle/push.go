@@ -0,0 +1,63 @@
+package bundle
+
+import (
+	"os"
+
+	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
+	"github.com/reposaur/reposaur/internal/oci"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+)
+
+type pushParams struct {
+	bundle cmdutil.BundleOptions
+}
+
+func newPushCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "push <BUNDLE> <oci://REGISTRY/REPOSITORY:TAG>",
+		Short: "Pushes a bundle to an OCI registry",
+		Long:  "Pushes a bundle to an OCI registry",
+	}
+
+	var (
+		params = &pushParams{}
+		flags  = cmd.Flags()
+	)
+
+	cmdutil.AddRegistryFlags(flags, &params.bundle)
+
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		var (
+			ctx    = cmd.Context()
+			logger = zerolog.Ctx(ctx)
+		)
+
+		if len(args) != 2 {
+			logger.Fatal().Msgf("exactly 2 arguments required, got %d", len(args))
+		}
+
+		ref, err := oci.ParseReference(args[1])
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid reference")
+		}
+
+		b, err := os.ReadFile(args[0])
+		if err != nil {
+			logger.Fatal().Err(err).Msg("could not read bundle")
+		}
+
+		client := oci.NewClient(params.bundle.RegistryUsername, params.bundle.RegistryPassword)
+
+		digest, err := client.Push(ctx, ref, b)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("could not push bundle")
+		}
+
+		ref.Digest = digest
+
+		logger.Info().Msgf("bundle pushed to %s", ref)
+	}
+
+	return cmd
+}
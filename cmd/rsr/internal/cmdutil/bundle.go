@@ -0,0 +1,79 @@
+package cmdutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/keys"
+	"github.com/reposaur/reposaur/pkg/sdk"
+)
+
+// BundleSDKOptions returns the SDK options that control how bundles
+// are fetched and verified.
+func BundleSDKOptions(opts *BundleOptions) ([]sdk.Option, error) {
+	verification, err := NewBundleVerification(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return []sdk.Option{
+		sdk.WithBundleCacheDir(opts.CacheDir),
+		sdk.WithRegistryCredentials(opts.RegistryUsername, opts.RegistryPassword),
+		sdk.WithBundleVerification(verification),
+		sdk.WithSkipBundleVerification(opts.SkipVerify),
+	}, nil
+}
+
+// NewBundleVerification returns the config used to verify bundle
+// signatures. The verification key is either a key set, i.e. a JSON
+// file mapping key IDs to their key and algorithm, or a single key
+// identified by opts.VerificationKeyID. Returns nil if no verification
+// key is set.
+func NewBundleVerification(opts *BundleOptions) (*bundle.VerificationConfig, error) {
+	if opts.VerificationKey == "" {
+		return nil, nil
+	}
+
+	key, err := readKey(opts.VerificationKey)
+	if err != nil {
+		return nil, fmt.Errorf("read verification key: %w", err)
+	}
+
+	if json.Valid([]byte(key)) {
+		keySet, err := keys.ParseKeysConfig([]byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("parse key set: %w", err)
+		}
+
+		// Signatures identify the key in the key set.
+		return bundle.NewVerificationConfig(keySet, "", "", nil), nil
+	}
+
+	return bundle.NewVerificationConfig(map[string]*keys.Config{
+		opts.VerificationKeyID: {Key: key, Algorithm: opts.VerificationAlg},
+	}, opts.VerificationKeyID, "", nil), nil
+}
+
+// readKey returns the key in value, either a PEM encoded key, a secret
+// or the path to a file with one of them. Values are only read as paths
+// if they're regular files, since inline keys can't be reliably told
+// apart from paths, e.g. base64 contains slashes.
+func readKey(value string) (string, error) {
+	if strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		return value, nil
+	}
+
+	if info, err := os.Stat(value); err != nil || !info.Mode().IsRegular() {
+		return value, nil
+	}
+
+	b, err := os.ReadFile(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,43 @@
+package cmdutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewBundleVerification(t *testing.T) {
+	var (
+		// Inline keys can have path segments longer than
+		// the maximum length of a file name.
+		secret = strings.Repeat("a", 300) + "/" + strings.Repeat("b", 300)
+		path   = filepath.Join(t.TempDir(), "key.pem")
+		pem    = "-----BEGIN PUBLIC KEY-----\n" + secret + "\n-----END PUBLIC KEY-----\n"
+	)
+
+	if err := os.WriteFile(path, []byte(pem), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		secret: secret,
+		pem:    pem,
+		path:   pem,
+	}
+
+	for value, expected := range tests {
+		config, err := NewBundleVerification(&BundleOptions{
+			VerificationKey:   value,
+			VerificationKeyID: "default",
+			VerificationAlg:   "HS256",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if key := config.PublicKeys["default"].Key; key != expected {
+			t.Fatalf("expected key '%.20s...' got '%.20s...'", expected, key)
+		}
+	}
+}
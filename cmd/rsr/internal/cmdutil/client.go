@@ -0,0 +1,157 @@
+package cmdutil
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+
+	giteaclient "github.com/reposaur/reposaur/provider/gitea/client"
+	githubclient "github.com/reposaur/reposaur/provider/github/client"
+	gitlabclient "github.com/reposaur/reposaur/provider/gitlab/client"
+	"github.com/rs/zerolog"
+)
+
+// NewGitHubClient returns a GitHub client configured from opts. If GitHub App
+// options are set an installation client is returned, otherwise if a token
+// is set a token client is returned. Returns an unauthenticated client if
+// neither are set.
+func NewGitHubClient(ctx context.Context, opts *GitHubClientOptions) (*githubclient.Client, error) {
+	cache, err := NewGitHubCache(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures, err := newGitHubFixtures(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var client *githubclient.Client
+
+	switch {
+	case opts.AppID != 0 && opts.InstallationID != 0 && opts.AppPrivateKey != "":
+		client, err = githubclient.NewAppClient(ctx, opts.BaseURL, opts.AppID, opts.InstallationID, []byte(opts.AppPrivateKey))
+		if err != nil {
+			return nil, err
+		}
+
+	case opts.Token != "":
+		client = githubclient.NewTokenClient(ctx, opts.Token)
+
+	default:
+		client = githubclient.NewClient(nil)
+	}
+
+	if opts.BaseURL != "" {
+		baseURL, err := url.Parse(opts.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+
+		client.BaseURL = baseURL
+	}
+
+	client.Cache = cache
+	client.Fixtures = fixtures
+
+	return client, nil
+}
+
+// SaveGitHubFixtures writes the requests and responses recorded by c,
+// if any.
+func SaveGitHubFixtures(c *githubclient.Client) error {
+	if c.Fixtures == nil {
+		return nil
+	}
+
+	return c.Fixtures.Save()
+}
+
+// newGitHubFixtures returns the fixtures configured from opts. Returns
+// nil if neither recording nor replaying.
+func newGitHubFixtures(opts *GitHubClientOptions) (*githubclient.Fixtures, error) {
+	switch {
+	case opts.RecordFile != "" && opts.ReplayFile != "":
+		return nil, errors.New("--record and --replay can't be used together")
+
+	case opts.RecordFile != "":
+		return githubclient.NewRecorder(opts.RecordFile), nil
+
+	case opts.ReplayFile != "":
+		return githubclient.NewReplayer(opts.ReplayFile)
+	}
+
+	return nil, nil
+}
+
+// NewGitHubCache returns the response cache configured from opts. Returns
+// nil if caching is disabled, i.e. no cache directory is set or NoCache
+// is set. The cache isn't bounded, so it's only enabled on request.
+func NewGitHubCache(opts *GitHubClientOptions) (*githubclient.DiskCache, error) {
+	if opts.NoCache || opts.CacheDir == "" {
+		return nil, nil
+	}
+
+	return githubclient.NewDiskCache(opts.CacheDir)
+}
+
+// LogGitHubRateLimits logs the last known rate-limit state of every
+// resource used by c.
+func LogGitHubRateLimits(logger *zerolog.Logger, c *githubclient.Client) {
+	limits := c.RateLimits()
+
+	resources := make([]string, 0, len(limits))
+	for r := range limits {
+		resources = append(resources, r)
+	}
+
+	sort.Strings(resources)
+
+	for _, r := range resources {
+		l := limits[r]
+
+		logger.Debug().
+			Str("resource", l.Resource).
+			Int("limit", l.Limit).
+			Int("remaining", l.Remaining).
+			Time("reset", l.Reset).
+			Msg("GitHub rate limit")
+	}
+}
+
+// NewGitLabClient returns a GitLab client configured from opts.
+func NewGitLabClient(ctx context.Context, opts *GitLabClientOptions) (*gitlabclient.Client, error) {
+	client := gitlabclient.NewClient(nil)
+
+	if opts.Token != "" {
+		client = gitlabclient.NewTokenClient(ctx, opts.Token)
+	}
+
+	baseURL, err := url.Parse(opts.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client.BaseURL = baseURL
+
+	return client, nil
+}
+
+// NewGiteaClient returns a Gitea client configured from opts.
+func NewGiteaClient(ctx context.Context, opts *GiteaClientOptions) (*giteaclient.Client, error) {
+	client := giteaclient.NewClient(nil)
+
+	if opts.Token != "" {
+		client = giteaclient.NewTokenClient(ctx, opts.Token)
+	}
+
+	baseURL, err := url.Parse(opts.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client.BaseURL = baseURL
+
+	return client, nil
+}
@@ -0,0 +1,38 @@
+package cmdutil
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestNewGitHubClientBaseURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	tests := map[string]*GitHubClientOptions{
+		"unauthenticated": {},
+		"token":           {Token: "secret"},
+		"app":             {AppID: 1, InstallationID: 2, AppPrivateKey: string(privPEM)},
+	}
+
+	for name, opts := range tests {
+		opts.BaseURL = "https://github.example.com/api/v3/"
+
+		c, err := NewGitHubClient(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if c.BaseURL.String() != opts.BaseURL {
+			t.Errorf("%s: expected base URL %s got %s", name, opts.BaseURL, c.BaseURL)
+		}
+	}
+}
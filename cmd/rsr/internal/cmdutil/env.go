@@ -3,6 +3,7 @@ package cmdutil
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 )
 
@@ -34,3 +35,15 @@ func getInt64Env(keys ...string) int64 {
 
 	return intVal
 }
+
+// defaultCacheDir returns the directory named name in the reposaur user
+// cache directory. If the user cache directory is unknown, returns an
+// empty string.
+func defaultCacheDir(name string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "reposaur", name)
+}
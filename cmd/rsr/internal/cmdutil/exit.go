@@ -0,0 +1,16 @@
+package cmdutil
+
+// Exit codes returned by the commands.
+const (
+	// ExitOK means the command ran successfully and no
+	// failures were found.
+	ExitOK = 0
+
+	// ExitError means the command couldn't run or evaluate
+	// its inputs. It's the same code used by logger.Fatal().
+	ExitError = 1
+
+	// ExitViolations means the command ran successfully but
+	// found results at or above the failure threshold.
+	ExitViolations = 2
+)
@@ -1,7 +1,13 @@
 package cmdutil
 
 import (
+	"time"
+
+	"github.com/reposaur/reposaur/pkg/output"
+	giteaclient "github.com/reposaur/reposaur/provider/gitea/client"
+	"github.com/reposaur/reposaur/provider/github"
 	githubclient "github.com/reposaur/reposaur/provider/github/client"
+	gitlabclient "github.com/reposaur/reposaur/provider/gitlab/client"
 	"github.com/spf13/pflag"
 )
 
@@ -20,16 +26,90 @@ type GitHubClientOptions struct {
 
 	// GitHub App Installation ID
 	InstallationID int64
+
+	// Maximum number of pages requested when paginating
+	MaxPages int
+
+	// Directory where responses are cached, if any
+	CacheDir string
+
+	// Disables the response cache, even if CacheDir is set
+	NoCache bool
+
+	// File where requests and responses are recorded
+	RecordFile string
+
+	// File where requests and responses are replayed from
+	ReplayFile string
+}
+
+type BundleOptions struct {
+	// Directory where remote bundles are cached
+	CacheDir string
+
+	// OCI registry username
+	RegistryUsername string
+
+	// OCI registry password or token
+	RegistryPassword string
+
+	// Public key, secret or key set used to verify bundle signatures
+	VerificationKey string
+
+	// ID of the verification key
+	VerificationKeyID string
+
+	// Algorithm of the verification key
+	VerificationAlg string
+
+	// Disables the verification of bundle signatures
+	SkipVerify bool
+}
+
+type GitLabClientOptions struct {
+	// GitLab API Base URL
+	BaseURL string
+
+	// GitLab Personal, Group or Project Access Token
+	Token string
+}
+
+type GiteaClientOptions struct {
+	// Gitea (or Forgejo) API Base URL
+	BaseURL string
+
+	// Gitea Access Token
+	Token string
 }
 
 func AddPolicyPathsFlag(flags *pflag.FlagSet, p *[]string) {
-	flags.StringSliceVarP(p, "policy", "p", []string{"."}, "path to policy files or directories")
+	flags.StringSliceVarP(p, "policy", "p", []string{"."}, "path to policy files, directories or bundles")
+}
+
+func AddDataPathsFlag(flags *pflag.FlagSet, p *[]string) {
+	flags.StringSliceVarP(p, "data", "d", nil, "path to JSON or YAML data files or directories available to policies")
+}
+
+func AddNamespaceFlag(flags *pflag.FlagSet, p *string) {
+	flags.StringVarP(p, "namespace", "n", "", "policy namespace to execute, derived from INPUT if empty")
 }
 
 func AddOutputFlag(flags *pflag.FlagSet, p *string) {
 	flags.StringVarP(p, "output", "o", "-", "output filename")
 }
 
+func AddFailOnFlag(flags *pflag.FlagSet, p *string) {
+	flags.StringVar(p, "fail-on", "error", "minimum severity of failed results that causes a non-zero exit code (error, warning or note)")
+}
+
+func AddFormatFlag(flags *pflag.FlagSet, p *string) {
+	flags.StringVarP(p, "format", "f", string(output.SarifFormat), "output format (sarif, json, jsonl, table or junit)")
+}
+
+func AddTestFormatFlag(flags *pflag.FlagSet, p *string) {
+	flags.StringVarP(p, "format", "f", "text", "output format (text or junit)")
+}
+
 func AddTraceFlag(flags *pflag.FlagSet, p *bool) {
 	flags.BoolVarP(p, "trace", "t", false, "enable tracing")
 }
@@ -42,6 +122,19 @@ func AddExperimentalFlag(flags *pflag.FlagSet, p *bool) {
 	flags.BoolVar(p, "experimental", false, "accepts the usage of experimental features")
 }
 
+func AddTimeoutFlags(flags *pflag.FlagSet, ruleTimeout, inputTimeout *time.Duration) {
+	flags.DurationVar(ruleTimeout, "rule-timeout", 0, "maximum duration of a rule evaluation (0 for no timeout)")
+	flags.DurationVar(inputTimeout, "input-timeout", 0, "maximum duration of the evaluation of an input (0 for no timeout)")
+}
+
+func AddAllowWritesFlag(flags *pflag.FlagSet, p *bool) {
+	flags.BoolVar(p, "allow-writes", false, "allows policies to send requests that modify data (e.g. POST, PUT, PATCH, DELETE or GraphQL mutations)")
+}
+
+func AddDryRunFlag(flags *pflag.FlagSet, p *bool) {
+	flags.BoolVar(p, "dry-run", false, "logs the requests that would modify data instead of sending them")
+}
+
 func AddGitHubFlags(flags *pflag.FlagSet, p *GitHubClientOptions) {
 	var (
 		defURL            = getEnv("GH_API_URL", "GITHUB_API_URL")
@@ -60,4 +153,78 @@ func AddGitHubFlags(flags *pflag.FlagSet, p *GitHubClientOptions) {
 	flags.Int64Var(&p.AppID, "github-app-id", defAppID, "id for GitHub App")
 	flags.StringVar(&p.AppPrivateKey, "github-app-private-key", defAppPrivKey, "base64-encoded private key for GitHub App")
 	flags.Int64Var(&p.InstallationID, "github-installation-id", defInstallationID, "installation ID for GitHub App")
+	flags.IntVar(&p.MaxPages, "github-max-pages", github.DefaultMaxPages, "maximum number of pages requested when paginating (0 for no limit)")
+	flags.StringVar(&p.CacheDir, "cache-dir", "", "directory where GitHub responses are cached, enabling the response cache")
+	flags.BoolVar(&p.NoCache, "no-cache", false, "disables the GitHub response cache, even if --cache-dir is set")
+}
+
+func AddFixtureFlags(flags *pflag.FlagSet, p *GitHubClientOptions) {
+	flags.StringVar(&p.RecordFile, "record", "", "records GitHub requests and responses to a fixture file")
+	flags.StringVar(&p.ReplayFile, "replay", "", "replays GitHub responses from a fixture file instead of sending requests")
+}
+
+func AddBundleFlags(flags *pflag.FlagSet, p *BundleOptions) {
+	flags.StringVar(&p.CacheDir, "bundle-cache-dir", defaultCacheDir("bundles"), "directory where remote policy bundles are cached")
+	flags.StringVar(&p.VerificationKey, "verification-key", "", "public key, secret or path to a key file or key set used to verify bundle signatures")
+	flags.StringVar(&p.VerificationKeyID, "verification-key-id", "default", "ID of the key passed in --verification-key")
+	flags.StringVar(&p.VerificationAlg, "verification-alg", "RS256", "algorithm of the key passed in --verification-key")
+	flags.BoolVar(&p.SkipVerify, "skip-verify", false, "disables the verification of bundle signatures")
+
+	AddRegistryFlags(flags, p)
+}
+
+func AddSigningFlags(flags *pflag.FlagSet, key, alg, keyID *string) {
+	flags.StringVar(key, "signing-key", "", "private key, secret or path to a key file used to sign the bundle")
+	flags.StringVar(alg, "signing-alg", "RS256", "algorithm of the key passed in --signing-key")
+	flags.StringVar(keyID, "signing-key-id", "default", "ID of the key passed in --signing-key, used to find the key when verifying")
+}
+
+func AddManifestFlags(flags *pflag.FlagSet, revision, minVersion *string, optimize *int) {
+	flags.StringVar(revision, "revision", "", "revision of the bundle (defaults to the git commit of the policies)")
+	flags.StringVar(minVersion, "min-version", "", "minimum version of Reposaur required to load the bundle")
+	flags.IntVarP(optimize, "optimize", "O", 0, "optimization level of the bundle (0 disables optimizations)")
+}
+
+func AddRegistryFlags(flags *pflag.FlagSet, p *BundleOptions) {
+	var (
+		defUsername = getEnv("REGISTRY_USERNAME")
+		defPassword = getEnv("REGISTRY_PASSWORD")
+	)
+
+	flags.StringVar(&p.RegistryUsername, "registry-username", defUsername, "username for OCI registries")
+	flags.StringVar(&p.RegistryPassword, "registry-password", defPassword, "password or token for OCI registries")
+}
+
+func AddGitLabFlags(flags *pflag.FlagSet, p *GitLabClientOptions) {
+	var (
+		defURL   = getEnv("GL_API_URL", "GITLAB_API_URL", "CI_API_V4_URL")
+		defToken = getEnv("GL_TOKEN", "GITLAB_TOKEN")
+	)
+
+	if defURL == "" {
+		defURL = gitlabclient.DefaultBaseURL
+	}
+
+	flags.StringVar(&p.BaseURL, "gitlab-api-url", defURL, "base url GitLab API")
+	flags.StringVar(&p.Token, "gitlab-token", defToken, "token for GitLab")
+}
+
+func AddGiteaFlags(flags *pflag.FlagSet, p *GiteaClientOptions) {
+	var (
+		defURL   = getEnv("GITEA_API_URL", "FORGEJO_API_URL")
+		defToken = getEnv("GITEA_TOKEN", "FORGEJO_TOKEN")
+	)
+
+	if defURL == "" {
+		defURL = giteaclient.DefaultBaseURL
+	}
+
+	flags.StringVar(&p.BaseURL, "gitea-api-url", defURL, "base url Gitea API")
+	flags.StringVar(&p.Token, "gitea-token", defToken, "token for Gitea")
+}
+
+func AddWebhookSecretFlag(flags *pflag.FlagSet, p *string) {
+	defSecret := getEnv("GH_WEBHOOK_SECRET", "GITHUB_WEBHOOK_SECRET")
+
+	flags.StringVar(p, "webhook-secret", defSecret, "secret used to verify GitHub webhook deliveries")
 }
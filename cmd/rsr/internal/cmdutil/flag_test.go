@@ -0,0 +1,44 @@
+package cmdutil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestGitHubCacheFlags(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "github")
+
+	tests := []struct {
+		args    []string
+		enabled bool
+	}{
+		{args: nil},
+		{args: []string{"--no-cache"}},
+		{args: []string{"--cache-dir", dir}, enabled: true},
+		{args: []string{"--cache-dir", dir, "--no-cache"}},
+	}
+
+	for _, tt := range tests {
+		var (
+			opts  GitHubClientOptions
+			flags = pflag.NewFlagSet("test", pflag.ContinueOnError)
+		)
+
+		AddGitHubFlags(flags, &opts)
+
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+
+		cache, err := NewGitHubCache(&opts)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+
+		if enabled := cache != nil; enabled != tt.enabled {
+			t.Errorf("%v: expected cache enabled to be %v got %v", tt.args, tt.enabled, enabled)
+		}
+	}
+}
@@ -53,3 +53,23 @@ func GetOutputWriter(ctx context.Context, filename string) (w io.WriteCloser, er
 
 	return file, nil
 }
+
+// IsTerminal returns true if w is a terminal and colors
+// weren't disabled through the NO_COLOR environment variable.
+func IsTerminal(w io.Writer) bool {
+	if getEnv("NO_COLOR") != "" {
+		return false
+	}
+
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -7,23 +7,37 @@ import (
 	"io"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
 	"github.com/reposaur/reposaur/pkg/output"
 	"github.com/reposaur/reposaur/pkg/sdk"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/gitea"
 	"github.com/reposaur/reposaur/provider/github"
-	githubclient "github.com/reposaur/reposaur/provider/github/client"
+	"github.com/reposaur/reposaur/provider/gitlab"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 )
 
 type execParams struct {
 	policyPaths    []string
+	dataPaths      []string
 	outputFilename string
 	inputFilename  string
+	namespace      string
+	failOn         string
+	format         string
 	enableTracing  bool
+	allowWrites    bool
+	dryRun         bool
+	ruleTimeout    time.Duration
+	inputTimeout   time.Duration
+	bundle         cmdutil.BundleOptions
 	github         cmdutil.GitHubClientOptions
+	gitlab         cmdutil.GitLabClientOptions
+	gitea          cmdutil.GiteaClientOptions
 }
 
 func NewCmd() *cobra.Command {
@@ -39,9 +53,20 @@ func NewCmd() *cobra.Command {
 	)
 
 	cmdutil.AddOutputFlag(flags, &params.outputFilename)
+	cmdutil.AddFormatFlag(flags, &params.format)
 	cmdutil.AddPolicyPathsFlag(flags, &params.policyPaths)
+	cmdutil.AddDataPathsFlag(flags, &params.dataPaths)
+	cmdutil.AddNamespaceFlag(flags, &params.namespace)
+	cmdutil.AddFailOnFlag(flags, &params.failOn)
 	cmdutil.AddTraceFlag(flags, &params.enableTracing)
+	cmdutil.AddAllowWritesFlag(flags, &params.allowWrites)
+	cmdutil.AddDryRunFlag(flags, &params.dryRun)
+	cmdutil.AddTimeoutFlags(flags, &params.ruleTimeout, &params.inputTimeout)
+	cmdutil.AddBundleFlags(flags, &params.bundle)
 	cmdutil.AddGitHubFlags(flags, &params.github)
+	cmdutil.AddFixtureFlags(flags, &params.github)
+	cmdutil.AddGitLabFlags(flags, &params.gitlab)
+	cmdutil.AddGiteaFlags(flags, &params.gitea)
 
 	cmd.Run = func(cmd *cobra.Command, args []string) {
 		var (
@@ -57,6 +82,10 @@ func NewCmd() *cobra.Command {
 			params.inputFilename = args[0]
 		}
 
+		if !output.IsValidSeverity(params.failOn) {
+			logger.Fatal().Msgf("invalid --fail-on severity '%s', expected error, warning or note", params.failOn)
+		}
+
 		inReader, err := cmdutil.GetInputReader(ctx, params.inputFilename)
 		if err != nil {
 			logger.Fatal().Err(err).Msg("failed to get input reader")
@@ -77,23 +106,55 @@ func NewCmd() *cobra.Command {
 			}
 		}()
 
-		githubProvider, err := newGitHubProvider(ctx, &params.github)
+		githubClient, err := cmdutil.NewGitHubClient(ctx, &params.github)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create GitHub client")
+		}
+
+		gitlabClient, err := cmdutil.NewGitLabClient(ctx, &params.gitlab)
 		if err != nil {
-			logger.Fatal().Err(err).Msg("failed to create GitHub provider")
+			logger.Fatal().Err(err).Msg("failed to create GitLab client")
+		}
+
+		giteaClient, err := cmdutil.NewGiteaClient(ctx, &params.gitea)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create Gitea client")
 		}
 
 		opts := []sdk.Option{
 			sdk.WithLogger(*logger),
-			sdk.WithProvider(githubProvider),
+			sdk.WithProvider(github.NewProvider(githubClient, github.WithMaxPages(params.github.MaxPages))),
+			sdk.WithProvider(gitlab.NewProvider(gitlabClient)),
+			sdk.WithProvider(gitea.NewProvider(giteaClient)),
 			sdk.WithTracingEnabled(params.enableTracing),
+			sdk.WithWriteAccess(params.allowWrites),
+			sdk.WithDryRun(params.dryRun),
+			sdk.WithRuleTimeout(params.ruleTimeout),
+			sdk.WithInputTimeout(params.inputTimeout),
+			sdk.WithData(params.dataPaths),
 		}
 
+		bundleOpts, err := cmdutil.BundleSDKOptions(&params.bundle)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid bundle options")
+		}
+
+		opts = append(opts, bundleOpts...)
+
 		rsr, err := sdk.New(ctx, params.policyPaths, opts...)
 		if err != nil {
 			logger.Fatal().Err(err).Msg("could not instantiate SDK")
 		}
 
-		runExec(ctx, rsr, inReader, outWriter)
+		code := runExec(ctx, rsr, params, inReader, outWriter)
+
+		cmdutil.LogGitHubRateLimits(logger, githubClient)
+
+		if err := cmdutil.SaveGitHubFixtures(githubClient); err != nil {
+			logger.Fatal().Err(err).Msg("failed to save fixtures")
+		}
+
+		os.Exit(code)
 	}
 
 	return cmd
@@ -101,7 +162,15 @@ func NewCmd() *cobra.Command {
 
 // runExec will execute the policies against the data available
 // in inReader. The resulting reports will be outputted to outWriter.
-func runExec(ctx context.Context, rsr *sdk.Reposaur, inReader io.ReadCloser, outWriter io.WriteCloser) {
+//
+// If params.namespace is empty, it's derived from each input. Otherwise, every
+// input is checked against the policies in that namespace.
+//
+// Inputs or rules that can't be evaluated don't stop the remaining ones
+// from being evaluated. Returns cmdutil.ExitError if any of them couldn't
+// be evaluated, cmdutil.ExitViolations if any report has failed results at
+// or above params.failOn severity or cmdutil.ExitOK otherwise.
+func runExec(ctx context.Context, rsr *sdk.Reposaur, params *execParams, inReader io.ReadCloser, outWriter io.WriteCloser) int {
 	startTime := time.Now()
 
 	var (
@@ -111,6 +180,14 @@ func runExec(ctx context.Context, rsr *sdk.Reposaur, inReader io.ReadCloser, out
 		reportsCh = make(chan output.Report)
 		reportsWg = sync.WaitGroup{}
 
+		// Only accessed by the goroutine outputting reports
+		// until reportsWg is done
+		hasFailures bool
+		hasErrors   bool
+
+		// Number of inputs that couldn't be checked
+		failedInputs int32
+
 		logger = zerolog.Ctx(ctx)
 	)
 
@@ -123,9 +200,31 @@ func runExec(ctx context.Context, rsr *sdk.Reposaur, inReader io.ReadCloser, out
 			go func(input interface{}) {
 				logger.Debug().Msg("processing input")
 
-				report, err := rsr.Check(ctx, input)
+				var (
+					report output.Report
+					err    error
+				)
+
+				if params.namespace != "" {
+					report, err = rsr.CheckNamespace(ctx, params.namespace, input)
+				} else {
+					report, err = rsr.Check(ctx, input)
+				}
+
+				var ambiguousErr *provider.AmbiguousNamespaceError
+				if errors.As(err, &ambiguousErr) {
+					logger.Debug().
+						Interface("namespaces", ambiguousErr.Namespaces).
+						Msg("input matches more than one namespace, use --namespace to choose one")
+				}
+
+				// The input is still reported, so that every
+				// output format shows it couldn't be evaluated.
 				if err != nil {
-					logger.Fatal().Err(err).Send()
+					logger.Error().Err(err).Msg("failed to check input")
+					atomic.AddInt32(&failedInputs, 1)
+					reportsCh <- output.NewErrorReport(err)
+					return
 				}
 
 				logger.Debug().Msg("done processing input")
@@ -135,18 +234,27 @@ func runExec(ctx context.Context, rsr *sdk.Reposaur, inReader io.ReadCloser, out
 		}
 	}()
 
+	enc, err := output.NewEncoder(
+		outWriter,
+		output.Format(params.format),
+		output.WithColors(cmdutil.IsTerminal(outWriter)),
+	)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create output encoder")
+	}
+
 	// Output reports
 	go func() {
-		enc := json.NewEncoder(outWriter)
-		enc.SetIndent("", "  ")
-
 		for report := range reportsCh {
-			sarif, err := output.NewSarifReport(report)
-			if err != nil {
-				logger.Fatal().Err(err).Send()
+			if report.HasFailures(params.failOn) {
+				hasFailures = true
 			}
 
-			if err := enc.Encode(sarif); err != nil {
+			if report.HasErrors() {
+				hasErrors = true
+			}
+
+			if err := enc.Encode(report); err != nil {
 				logger.Fatal().Err(err).Send()
 			}
 
@@ -191,25 +299,29 @@ func runExec(ctx context.Context, rsr *sdk.Reposaur, inReader io.ReadCloser, out
 	close(reportsCh)
 	logger.Debug().Msg("closed reports channel")
 
-	logger.Info().Dur("timeElapsed", time.Since(startTime)).Msg("done")
+	if err := enc.Close(); err != nil {
+		logger.Fatal().Err(err).Msg("failed to write output")
+	}
 
-	// TODO: should exit with 1 if there are failed results
-	os.Exit(0)
-}
+	if hasErrors || failedInputs > 0 {
+		logger.Error().
+			Dur("timeElapsed", time.Since(startTime)).
+			Int32("failedInputs", failedInputs).
+			Msg("done, some inputs or rules couldn't be evaluated")
 
-func newGitHubProvider(ctx context.Context, opts *cmdutil.GitHubClientOptions) (*github.GitHub, error) {
-	var client *githubclient.Client
+		return cmdutil.ExitError
+	}
 
-	if opts.AppID != 0 && opts.InstallationID != 0 && opts.AppPrivateKey != "" {
-		appClient, err := githubclient.NewAppClient(ctx, opts.BaseURL, opts.AppID, opts.InstallationID, []byte(opts.AppPrivateKey))
-		if err != nil {
-			return nil, err
-		}
+	if hasFailures {
+		logger.Error().
+			Dur("timeElapsed", time.Since(startTime)).
+			Str("failOn", params.failOn).
+			Msg("done, found failed results")
 
-		client = appClient
-	} else if opts.Token != "" {
-		client = githubclient.NewTokenClient(ctx, opts.Token)
+		return cmdutil.ExitViolations
 	}
 
-	return github.NewProvider(client), nil
+	logger.Info().Dur("timeElapsed", time.Since(startTime)).Msg("done")
+
+	return cmdutil.ExitOK
 }
@@ -0,0 +1,59 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
+	"github.com/reposaur/reposaur/pkg/output"
+	"github.com/reposaur/reposaur/pkg/sdk"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestRunExecInputErrors(t *testing.T) {
+	ctx := context.Background()
+
+	rsr, err := sdk.New(ctx, []string{"testdata/policy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		params = &execParams{format: string(output.JSONFormat), failOn: output.ErrorSeverity}
+		in     = io.NopCloser(strings.NewReader(`[{"full_name": "acme/api", "owner": {"login": "acme"}, "description": "API"}, {"foo": "bar"}]`))
+		buf    = &bytes.Buffer{}
+	)
+
+	if code := runExec(ctx, rsr, params, in, nopWriteCloser{buf}); code != cmdutil.ExitError {
+		t.Fatalf("expected exit code %d got %d", cmdutil.ExitError, code)
+	}
+
+	var errs []string
+
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var report output.Report
+		if err := dec.Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+
+		if report.Error != "" {
+			errs = append(errs, report.Error)
+		}
+	}
+
+	if len(errs) != 1 || errs[0] != "could not derive a valid namespace from data" {
+		t.Fatalf("expected the input error to be reported got %v", errs)
+	}
+}
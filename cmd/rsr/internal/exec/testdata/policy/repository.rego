@@ -0,0 +1,5 @@
+package github.repository
+
+violation_missing_description {
+	not input.description
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/reposaur/reposaur/cmd/rsr/internal/bundle"
 	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
 	"github.com/reposaur/reposaur/cmd/rsr/internal/exec"
+	"github.com/reposaur/reposaur/cmd/rsr/internal/scan"
+	"github.com/reposaur/reposaur/cmd/rsr/internal/serve"
 	"github.com/reposaur/reposaur/cmd/rsr/internal/test"
 	"github.com/reposaur/reposaur/internal/build"
 	"github.com/spf13/cobra"
@@ -34,6 +36,8 @@ func NewCmd() *cobra.Command {
 
 	cmd.AddCommand(
 		exec.NewCmd(),
+		scan.NewCmd(),
+		serve.NewCmd(),
 		test.NewCmd(),
 		bundle.NewCmd(),
 	)
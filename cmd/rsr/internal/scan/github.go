@@ -0,0 +1,190 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/github"
+	githubclient "github.com/reposaur/reposaur/provider/github/client"
+	"github.com/rs/zerolog"
+)
+
+// RepositoryFilter selects which repositories are scanned.
+type RepositoryFilter struct {
+	// Include has glob patterns matched against repository names. If
+	// not empty, only repositories matching a pattern are scanned.
+	Include []string
+
+	// Exclude has glob patterns matched against repository names.
+	// Repositories matching a pattern aren't scanned.
+	Exclude []string
+
+	// Topics, if not empty, only scans repositories with
+	// at least one of the topics.
+	Topics []string
+
+	// ExcludeTopics skips repositories with any of the topics.
+	ExcludeTopics []string
+
+	// IncludeArchived scans archived repositories too.
+	IncludeArchived bool
+}
+
+// Match returns true if repo passes the filter.
+func (f RepositoryFilter) Match(repo map[string]any) (bool, error) {
+	name, _ := repo["name"].(string)
+
+	if archived, _ := repo["archived"].(bool); archived && !f.IncludeArchived {
+		return false, nil
+	}
+
+	if len(f.Include) > 0 {
+		included, err := matchAny(f.Include, name)
+		if err != nil || !included {
+			return false, err
+		}
+	}
+
+	excluded, err := matchAny(f.Exclude, name)
+	if err != nil || excluded {
+		return false, err
+	}
+
+	var topics []string
+	if repoTopics, ok := repo["topics"].([]any); ok {
+		for _, t := range repoTopics {
+			if s, ok := t.(string); ok {
+				topics = append(topics, s)
+			}
+		}
+	}
+
+	if len(f.Topics) > 0 && !containsAny(topics, f.Topics) {
+		return false, nil
+	}
+
+	return !containsAny(topics, f.ExcludeTopics), nil
+}
+
+// GitHubScanner fetches the data of a GitHub organization.
+type GitHubScanner struct {
+	Client *githubclient.Client
+	Org    string
+	Filter RepositoryFilter
+}
+
+// Scan fetches the organization, its members, teams, repositories and their
+// open pull requests, calling fn with each one of them and its namespace.
+// Lists are fetched following every page. Failing to fetch the pull requests
+// of a repository is logged and the scan continues.
+func (s GitHubScanner) Scan(ctx context.Context, fn func(provider.Namespace, map[string]any)) error {
+	var (
+		logger = zerolog.Ctx(ctx)
+		org    = url.PathEscape(s.Org)
+	)
+
+	var orgData map[string]any
+
+	if _, err := s.Client.GetJSON(ctx, "/orgs/"+org, &orgData); err != nil {
+		return err
+	}
+
+	fn(github.OrganizationNamespace, orgData)
+
+	lists := []struct {
+		namespace provider.Namespace
+		path      string
+	}{
+		{github.UserNamespace, "/orgs/" + org + "/members?per_page=100"},
+		{github.TeamNamespace, "/orgs/" + org + "/teams?per_page=100"},
+	}
+
+	for _, l := range lists {
+		items, err := s.Client.GetAll(ctx, l.path, 0)
+		if err != nil {
+			return err
+		}
+
+		logger.Debug().Msgf("fetched %d %s objects", len(items), l.namespace)
+
+		for _, item := range items {
+			if m, ok := item.(map[string]any); ok {
+				fn(l.namespace, m)
+			}
+		}
+	}
+
+	repos, err := s.Client.GetAll(ctx, "/orgs/"+org+"/repos?type=all&per_page=100", 0)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug().Msgf("fetched %d repositories", len(repos))
+
+	for _, item := range repos {
+		repo, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		match, err := s.Filter.Match(repo)
+		if err != nil {
+			return err
+		}
+
+		if !match {
+			logger.Debug().Msgf("skipping repository %v", repo["full_name"])
+			continue
+		}
+
+		fn(github.RepositoryNamespace, repo)
+
+		fullName, _ := repo["full_name"].(string)
+
+		// A repository whose pull requests can't be fetched, e.g. with
+		// pull requests disabled, shouldn't stop the whole scan.
+		pulls, err := s.Client.GetAll(ctx, "/repos/"+fullName+"/pulls?state=open&per_page=100", 0)
+		if err != nil {
+			logger.Error().Err(err).Str("repository", fullName).Msg("failed to fetch pull requests")
+			continue
+		}
+
+		for _, pull := range pulls {
+			if m, ok := pull.(map[string]any); ok {
+				fn(github.PullRequestNamespace, m)
+			}
+		}
+	}
+
+	return nil
+}
+
+func matchAny(patterns []string, name string) (bool, error) {
+	for _, p := range patterns {
+		match, err := path.Match(p, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern '%s': %w", p, err)
+		}
+
+		if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, v := range values {
+		for _, c := range candidates {
+			if v == c {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,221 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
+	"github.com/reposaur/reposaur/pkg/output"
+	"github.com/reposaur/reposaur/pkg/sdk"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/github"
+	githubclient "github.com/reposaur/reposaur/provider/github/client"
+)
+
+func newGitHubServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var srv *httptest.Server
+
+	mux := http.NewServeMux()
+
+	writeJSON := func(w http.ResponseWriter, v any) {
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			t.Error(err)
+		}
+	}
+
+	mux.HandleFunc("/orgs/acme", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"login": "acme", "members_url": "https://example.com"})
+	})
+
+	mux.HandleFunc("/orgs/acme/members", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []any{map[string]any{"login": "crqra"}})
+	})
+
+	mux.HandleFunc("/orgs/acme/teams", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []any{map[string]any{"slug": "maintainers"}})
+	})
+
+	mux.HandleFunc("/orgs/acme/repos", func(w http.ResponseWriter, r *http.Request) {
+		owner := map[string]any{"login": "acme"}
+
+		if r.URL.Query().Get("page") == "2" {
+			writeJSON(w, []any{
+				map[string]any{"name": "archived", "full_name": "acme/archived", "owner": owner, "archived": true},
+				map[string]any{"name": "docs", "full_name": "acme/docs", "owner": owner, "topics": []any{"docs"}},
+			})
+			return
+		}
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/acme/repos?page=2>; rel="next", <%s/orgs/acme/repos?page=2>; rel="last"`, srv.URL, srv.URL))
+		writeJSON(w, []any{
+			map[string]any{"name": "api", "full_name": "acme/api", "owner": owner, "description": "API"},
+			map[string]any{"name": "web", "full_name": "acme/web", "owner": owner},
+		})
+	})
+
+	mux.HandleFunc("/repos/acme/api/pulls", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []any{map[string]any{"number": 1, "base": map[string]any{}, "head": map[string]any{}}})
+	})
+
+	mux.HandleFunc("/repos/acme/web/pulls", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []any{})
+	})
+
+	mux.HandleFunc("/repos/acme/docs/pulls", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected acme/docs to be filtered out")
+	})
+
+	mux.HandleFunc("/repos/acme/archived/pulls", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected acme/archived to be filtered out")
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server) *githubclient.Client {
+	t.Helper()
+
+	c := githubclient.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL)
+
+	return c
+}
+
+func TestGitHubScannerScan(t *testing.T) {
+	srv := newGitHubServer(t)
+
+	scanner := GitHubScanner{
+		Client: newTestClient(t, srv),
+		Org:    "acme",
+		Filter: RepositoryFilter{ExcludeTopics: []string{"docs"}},
+	}
+
+	counts := map[provider.Namespace]int{}
+
+	err := scanner.Scan(context.Background(), func(namespace provider.Namespace, _ map[string]any) {
+		counts[namespace]++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[provider.Namespace]int{
+		github.OrganizationNamespace: 1,
+		github.UserNamespace:         1,
+		github.TeamNamespace:         1,
+		github.RepositoryNamespace:   2,
+		github.PullRequestNamespace:  1,
+	}
+
+	for namespace, count := range expected {
+		if counts[namespace] != count {
+			t.Errorf("expected %d %s objects got %d", count, namespace, counts[namespace])
+		}
+	}
+}
+
+func TestGitHubScannerScanPullsError(t *testing.T) {
+	srv := newGitHubServer(t)
+
+	// acme/api pull requests fail, the remaining requests are forwarded
+	// to srv. The client retries server errors with backoff, so this
+	// test takes a few seconds.
+	target, _ := url.Parse(srv.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/acme/api/pulls" {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(failing.Close)
+
+	scanner := GitHubScanner{
+		Client: newTestClient(t, failing),
+		Org:    "acme",
+		Filter: RepositoryFilter{ExcludeTopics: []string{"docs"}},
+	}
+
+	var repos []any
+
+	err := scanner.Scan(context.Background(), func(namespace provider.Namespace, data map[string]any) {
+		if namespace == github.RepositoryNamespace {
+			repos = append(repos, data["full_name"])
+		}
+
+		if namespace == github.PullRequestNamespace {
+			t.Errorf("expected no pull requests got %v", data)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(repos) != "[acme/api acme/web]" {
+		t.Fatalf("expected every repository to be scanned got %v", repos)
+	}
+}
+
+func TestRunScan(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		srv    = newGitHubServer(t)
+		client = newTestClient(t, srv)
+	)
+
+	rsr, err := sdk.New(ctx, []string{"testdata/policy"}, sdk.WithProvider(github.NewProvider(client)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := GitHubScanner{
+		Client: client,
+		Org:    "acme",
+		Filter: RepositoryFilter{Include: []string{"api", "web"}},
+	}
+
+	params := &scanParams{format: string(output.JSONFormat), failOn: output.ErrorSeverity}
+	buf := &bytes.Buffer{}
+
+	if code := runScan(ctx, rsr, scanner, params, buf); code != cmdutil.ExitViolations {
+		t.Fatalf("expected exit code %d got %d", cmdutil.ExitViolations, code)
+	}
+
+	var reports []output.Report
+	if err := json.Unmarshal(buf.Bytes(), &reports); err != nil {
+		t.Fatal(err)
+	}
+
+	var failed []string
+	for _, report := range reports {
+		for _, result := range report.Results {
+			if result.Failed() {
+				failed = append(failed, fmt.Sprintf("%s:%v", result.Rule.UID(), report.Properties["repo"]))
+			}
+		}
+	}
+
+	expected := []string{
+		"github.pull_request/warn/missing_body:<nil>",
+		"github.repository/violation/missing_description:web",
+	}
+
+	if fmt.Sprint(failed) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected failed results %v", failed)
+	}
+}
@@ -0,0 +1,282 @@
+package scan
+
+import (
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
+	"github.com/reposaur/reposaur/pkg/output"
+	"github.com/reposaur/reposaur/pkg/sdk"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/github"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+)
+
+// Scanner fetches data from a provider, calling fn with
+// each object fetched and its namespace.
+type Scanner interface {
+	Scan(ctx context.Context, fn func(provider.Namespace, map[string]any)) error
+}
+
+type scanParams struct {
+	policyPaths    []string
+	outputFilename string
+	format         string
+	failOn         string
+	enableTracing  bool
+	allowWrites    bool
+	dryRun         bool
+	ruleTimeout    time.Duration
+	inputTimeout   time.Duration
+	bundle         cmdutil.BundleOptions
+}
+
+type githubParams struct {
+	scanParams
+
+	org    string
+	filter RepositoryFilter
+	github cmdutil.GitHubClientOptions
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Fetches data from a provider and executes policies against it",
+		Long:  "Fetches data from a provider and executes policies against it",
+	}
+
+	cmd.AddCommand(newGitHubCmd())
+
+	return cmd
+}
+
+func newGitHubCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "github --org ORG [-p POLICY_PATH...] [-o OUTPUT]",
+		Short: "Executes policies against a GitHub organization",
+		Long:  "Executes policies against a GitHub organization, its members, teams, repositories and open pull requests",
+	}
+
+	var (
+		params = &githubParams{}
+		flags  = cmd.Flags()
+	)
+
+	addScanFlags(cmd, &params.scanParams)
+	cmdutil.AddGitHubFlags(flags, &params.github)
+
+	flags.StringVar(&params.org, "org", "", "organization to scan")
+	flags.StringSliceVar(&params.filter.Include, "include-repo", nil, "only scan repositories with names matching these glob patterns")
+	flags.StringSliceVar(&params.filter.Exclude, "exclude-repo", nil, "skip repositories with names matching these glob patterns")
+	flags.StringSliceVar(&params.filter.Topics, "topic", nil, "only scan repositories with at least one of these topics")
+	flags.StringSliceVar(&params.filter.ExcludeTopics, "exclude-topic", nil, "skip repositories with any of these topics")
+	flags.BoolVar(&params.filter.IncludeArchived, "include-archived", false, "scan archived repositories")
+
+	_ = cmd.MarkFlagRequired("org")
+
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		var (
+			ctx    = cmd.Context()
+			logger = zerolog.Ctx(ctx)
+		)
+
+		if !output.IsValidSeverity(params.failOn) {
+			logger.Fatal().Msgf("invalid --fail-on severity '%s', expected error, warning or note", params.failOn)
+		}
+
+		client, err := cmdutil.NewGitHubClient(ctx, &params.github)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create GitHub client")
+		}
+
+		opts := []sdk.Option{
+			sdk.WithLogger(*logger),
+			sdk.WithProvider(github.NewProvider(client, github.WithMaxPages(params.github.MaxPages))),
+			sdk.WithTracingEnabled(params.enableTracing),
+			sdk.WithWriteAccess(params.allowWrites),
+			sdk.WithDryRun(params.dryRun),
+			sdk.WithRuleTimeout(params.ruleTimeout),
+			sdk.WithInputTimeout(params.inputTimeout),
+		}
+
+		bundleOpts, err := cmdutil.BundleSDKOptions(&params.bundle)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid bundle options")
+		}
+
+		opts = append(opts, bundleOpts...)
+
+		rsr, err := sdk.New(ctx, params.policyPaths, opts...)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("could not instantiate SDK")
+		}
+
+		outWriter, err := cmdutil.GetOutputWriter(ctx, params.outputFilename)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to get output writer")
+		}
+
+		scanner := GitHubScanner{
+			Client: client,
+			Org:    params.org,
+			Filter: params.filter,
+		}
+
+		code := runScan(ctx, rsr, scanner, &params.scanParams, outWriter)
+
+		cmdutil.LogGitHubRateLimits(logger, client)
+
+		if err := outWriter.Close(); err != nil {
+			logger.Fatal().Err(err).Msg("failed to close output writer")
+		}
+
+		os.Exit(code)
+	}
+
+	return cmd
+}
+
+func addScanFlags(cmd *cobra.Command, params *scanParams) {
+	flags := cmd.Flags()
+
+	cmdutil.AddOutputFlag(flags, &params.outputFilename)
+	cmdutil.AddFormatFlag(flags, &params.format)
+	cmdutil.AddFailOnFlag(flags, &params.failOn)
+	cmdutil.AddPolicyPathsFlag(flags, &params.policyPaths)
+	cmdutil.AddTraceFlag(flags, &params.enableTracing)
+	cmdutil.AddAllowWritesFlag(flags, &params.allowWrites)
+	cmdutil.AddDryRunFlag(flags, &params.dryRun)
+	cmdutil.AddTimeoutFlags(flags, &params.ruleTimeout, &params.inputTimeout)
+	cmdutil.AddBundleFlags(flags, &params.bundle)
+}
+
+// runScan fetches every object using scanner and executes the policies
+// against them. The resulting reports are combined and written to outWriter.
+//
+// Returns cmdutil.ExitError if the data couldn't be fetched or an object
+// or rule couldn't be evaluated, cmdutil.ExitViolations if any report has failed
+// results at or above params.failOn severity or cmdutil.ExitOK otherwise.
+func runScan(ctx context.Context, rsr *sdk.Reposaur, scanner Scanner, params *scanParams, outWriter io.Writer) int {
+	type object struct {
+		namespace provider.Namespace
+		data      map[string]any
+	}
+
+	var (
+		startTime = time.Now()
+		logger    = zerolog.Ctx(ctx)
+		objects   []object
+	)
+
+	err := scanner.Scan(ctx, func(namespace provider.Namespace, data map[string]any) {
+		// Scanners fetch every kind of object, most policies
+		// only cover a few of them.
+		if !rsr.HasNamespace(string(namespace)) {
+			return
+		}
+
+		objects = append(objects, object{namespace, data})
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch data")
+		return cmdutil.ExitError
+	}
+
+	logger.Debug().Msgf("fetched %d objects", len(objects))
+
+	var (
+		reports = make([]*output.Report, len(objects))
+		indexCh = make(chan int)
+		wg      = sync.WaitGroup{}
+	)
+
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexCh {
+				obj := objects[i]
+
+				report, err := rsr.CheckNamespace(ctx, string(obj.namespace), obj.data)
+				if err != nil {
+					logger.Error().Err(err).Str("namespace", string(obj.namespace)).Msg("failed to check object")
+					continue
+				}
+
+				reports[i] = &report
+			}
+		}()
+	}
+
+	for i := range objects {
+		indexCh <- i
+	}
+
+	close(indexCh)
+	wg.Wait()
+
+	enc, err := output.NewEncoder(
+		outWriter,
+		output.Format(params.format),
+		output.WithColors(cmdutil.IsTerminal(outWriter)),
+		output.WithCombined(true),
+	)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create output encoder")
+		return cmdutil.ExitError
+	}
+
+	var hasErrors, hasFailures bool
+
+	for _, report := range reports {
+		if report == nil {
+			hasErrors = true
+			continue
+		}
+
+		if report.HasFailures(params.failOn) {
+			hasFailures = true
+		}
+
+		if report.HasErrors() {
+			hasErrors = true
+		}
+
+		if err := enc.Encode(*report); err != nil {
+			logger.Error().Err(err).Msg("failed to write report")
+			return cmdutil.ExitError
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		logger.Error().Err(err).Msg("failed to write output")
+		return cmdutil.ExitError
+	}
+
+	doneLogger := logger.With().
+		Int("objects", len(objects)).
+		Dur("timeElapsed", time.Since(startTime)).
+		Logger()
+
+	switch {
+	case hasErrors:
+		doneLogger.Error().Msg("done, some objects or rules couldn't be evaluated")
+		return cmdutil.ExitError
+
+	case hasFailures:
+		doneLogger.Error().Str("failOn", params.failOn).Msg("done, found failed results")
+		return cmdutil.ExitViolations
+	}
+
+	doneLogger.Info().Msg("done")
+
+	return cmdutil.ExitOK
+}
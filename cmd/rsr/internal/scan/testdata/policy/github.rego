@@ -0,0 +1,7 @@
+package github.repository
+
+# METADATA
+# title: Repository has a description
+violation_missing_description {
+	not input.description
+}
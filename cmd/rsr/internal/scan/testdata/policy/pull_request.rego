@@ -0,0 +1,7 @@
+package github.pull_request
+
+# METADATA
+# title: Pull request has a description
+warn_missing_body {
+	not input.body
+}
@@ -0,0 +1,194 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
+	"github.com/reposaur/reposaur/pkg/output"
+	"github.com/reposaur/reposaur/pkg/sdk"
+	"github.com/reposaur/reposaur/provider/github"
+	githubclient "github.com/reposaur/reposaur/provider/github/client"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+)
+
+type serveParams struct {
+	policyPaths   []string
+	addr          string
+	webhookSecret string
+	publish       string
+	failOn        string
+	enableTracing bool
+	allowWrites   bool
+	dryRun        bool
+	ruleTimeout   time.Duration
+	inputTimeout  time.Duration
+	bundle        cmdutil.BundleOptions
+	github        cmdutil.GitHubClientOptions
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve [-p POLICY_PATH...] [--addr ADDR]",
+		Short: "Runs a GitHub App webhook server",
+		Long:  "Runs a GitHub App webhook server that executes policies against the events received and publishes the results as check runs or commit statuses",
+	}
+
+	var (
+		params = &serveParams{}
+		flags  = cmd.Flags()
+	)
+
+	cmdutil.AddPolicyPathsFlag(flags, &params.policyPaths)
+	cmdutil.AddFailOnFlag(flags, &params.failOn)
+	cmdutil.AddTraceFlag(flags, &params.enableTracing)
+	cmdutil.AddAllowWritesFlag(flags, &params.allowWrites)
+	cmdutil.AddDryRunFlag(flags, &params.dryRun)
+	cmdutil.AddTimeoutFlags(flags, &params.ruleTimeout, &params.inputTimeout)
+	cmdutil.AddBundleFlags(flags, &params.bundle)
+	cmdutil.AddGitHubFlags(flags, &params.github)
+	cmdutil.AddWebhookSecretFlag(flags, &params.webhookSecret)
+
+	flags.StringVar(&params.addr, "addr", ":8080", "address to listen on")
+	flags.StringVar(&params.publish, "publish", PublishCheckRun, "how to publish results (check-run or status)")
+
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		var (
+			ctx    = cmd.Context()
+			logger = zerolog.Ctx(ctx)
+		)
+
+		if !output.IsValidSeverity(params.failOn) {
+			logger.Fatal().Msgf("invalid --fail-on severity '%s', expected error, warning or note", params.failOn)
+		}
+
+		if params.publish != PublishCheckRun && params.publish != PublishStatus {
+			logger.Fatal().Msgf("invalid --publish '%s', expected check-run or status", params.publish)
+		}
+
+		if params.github.AppID == 0 || params.github.AppPrivateKey == "" {
+			logger.Fatal().Msg("GitHub App ID and private key are required")
+		}
+
+		if params.webhookSecret == "" {
+			logger.Fatal().Msg("webhook secret is required")
+		}
+
+		opts := []sdk.Option{
+			sdk.WithLogger(*logger),
+			sdk.WithProvider(github.NewProvider(nil, github.WithMaxPages(params.github.MaxPages))),
+			sdk.WithTracingEnabled(params.enableTracing),
+			sdk.WithWriteAccess(params.allowWrites),
+			sdk.WithDryRun(params.dryRun),
+			sdk.WithRuleTimeout(params.ruleTimeout),
+			sdk.WithInputTimeout(params.inputTimeout),
+		}
+
+		bundleOpts, err := cmdutil.BundleSDKOptions(&params.bundle)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid bundle options")
+		}
+
+		opts = append(opts, bundleOpts...)
+
+		rsr, err := sdk.New(ctx, params.policyPaths, opts...)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("could not instantiate SDK")
+		}
+
+		handler := &WebhookHandler{
+			Secret:    []byte(params.webhookSecret),
+			Reposaur:  rsr,
+			NewClient: newInstallationClientFunc(&params.github),
+			Publish:   params.publish,
+			FailOn:    params.failOn,
+			Logger:    *logger,
+		}
+
+		runServe(ctx, params.addr, handler)
+	}
+
+	return cmd
+}
+
+// runServe serves handler at addr until an interrupt or termination
+// signal is received. Pending deliveries are processed before returning.
+func runServe(ctx context.Context, addr string, handler *WebhookHandler) {
+	logger := zerolog.Ctx(ctx)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Error().Err(err).Msg("failed to shutdown server")
+		}
+	}()
+
+	logger.Info().Msgf("listening on %s", addr)
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal().Err(err).Msg("failed to serve")
+	}
+
+	handler.Wait()
+
+	logger.Info().Msg("done")
+}
+
+// newInstallationClientFunc returns a ClientFunc that creates a GitHub App
+// installation client, reusing it for the following events of the same
+// installation so that access tokens are cached.
+func newInstallationClientFunc(opts *cmdutil.GitHubClientOptions) ClientFunc {
+	var (
+		mu      sync.Mutex
+		clients = map[int64]*githubclient.Client{}
+	)
+
+	return func(ctx context.Context, installationID int64) (*githubclient.Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if c, ok := clients[installationID]; ok {
+			return c, nil
+		}
+
+		c, err := githubclient.NewAppClient(ctx, opts.BaseURL, opts.AppID, installationID, []byte(opts.AppPrivateKey))
+		if err != nil {
+			return nil, err
+		}
+
+		c.BaseURL, err = url.Parse(opts.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Cache, err = cmdutil.NewGitHubCache(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		clients[installationID] = c
+
+		return c, nil
+	}
+}
@@ -0,0 +1,7 @@
+package github.pull_request
+
+# METADATA
+# title: Pull request has a description
+violation_missing_body {
+	not is_string(input.body)
+}
@@ -0,0 +1,13 @@
+package github.repository
+
+# METADATA
+# title: Default branch is protected
+violation_default_branch_not_protected {
+	resp := github.request("GET /repos/{owner}/{repo}/branches/{branch}/protection", {
+		"owner": input.owner.login,
+		"repo": input.name,
+		"branch": input.default_branch,
+	})
+
+	resp.status == 404
+}
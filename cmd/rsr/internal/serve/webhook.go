@@ -0,0 +1,393 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
+	"github.com/reposaur/reposaur/pkg/output"
+	"github.com/reposaur/reposaur/pkg/sdk"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/github"
+	githubclient "github.com/reposaur/reposaur/provider/github/client"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// PublishCheckRun publishes results as check runs.
+	PublishCheckRun = "check-run"
+
+	// PublishStatus publishes results as commit statuses.
+	PublishStatus = "status"
+
+	// maxPayloadSize is the maximum size of a webhook payload
+	// delivered by GitHub (25 MB).
+	maxPayloadSize = 25 << 20
+)
+
+var errInvalidSignature = errors.New("invalid webhook signature")
+
+// eventObjects maps the supported events to the namespace and
+// key of the object in the event payload.
+var eventObjects = map[string]struct {
+	namespace provider.Namespace
+	key       string
+}{
+	"issues":       {github.IssueNamespace, "issue"},
+	"membership":   {github.UserNamespace, "member"},
+	"member":       {github.UserNamespace, "member"},
+	"organization": {github.OrganizationNamespace, "organization"},
+	"pull_request": {github.PullRequestNamespace, "pull_request"},
+	"push":         {github.RepositoryNamespace, "repository"},
+	"repository":   {github.RepositoryNamespace, "repository"},
+	"team":         {github.TeamNamespace, "team"},
+}
+
+// ClientFunc returns a GitHub client scoped to a GitHub App installation.
+type ClientFunc func(ctx context.Context, installationID int64) (*githubclient.Client, error)
+
+// WebhookHandler is an http.Handler that receives GitHub webhook deliveries,
+// executes the policies against the object of each event and publishes the
+// results on the event's head commit.
+//
+// Deliveries are processed in the background, after responding to GitHub.
+type WebhookHandler struct {
+	// Secret used to verify the X-Hub-Signature-256 header.
+	Secret []byte
+
+	// Reposaur executes the policies.
+	Reposaur *sdk.Reposaur
+
+	// NewClient returns the client used to evaluate and publish
+	// the results of an event, scoped to its installation.
+	NewClient ClientFunc
+
+	// Publish is either PublishCheckRun or PublishStatus.
+	Publish string
+
+	// FailOn is the minimum severity of failed results that
+	// cause the check run or status to fail.
+	FailOn string
+
+	Logger zerolog.Logger
+
+	wg sync.WaitGroup
+}
+
+type webhookPayload struct {
+	Installation *struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+
+	Repository *struct {
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+
+	PullRequest *struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+
+	After   string `json:"after"`
+	Deleted bool   `json:"deleted"`
+}
+
+// nullSHA is the after commit of push events that delete a ref.
+const nullSHA = "0000000000000000000000000000000000000000"
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.verifySignature(r.Header.Get("X-Hub-Signature-256"), body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var (
+		event      = r.Header.Get("X-GitHub-Event")
+		deliveryID = r.Header.Get("X-GitHub-Delivery")
+		logger     = h.Logger.With().Str("event", event).Str("delivery", deliveryID).Logger()
+	)
+
+	if event == "ping" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	obj, ok := eventObjects[event]
+	if !ok {
+		logger.Debug().Msg("ignoring unsupported event")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var (
+		payload webhookPayload
+		data    map[string]any
+	)
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	// There's no commit to publish the results on once a ref is deleted.
+	if payload.Deleted || payload.After == nullSHA {
+		logger.Debug().Msg("ignoring push deleting a ref")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if err := json.Unmarshal(body, &data); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	objData, ok := data[obj.key].(map[string]any)
+	if !ok {
+		http.Error(w, fmt.Sprintf("payload doesn't have a %s", obj.key), http.StatusBadRequest)
+		return
+	}
+
+	if payload.Installation == nil {
+		http.Error(w, "payload doesn't have an installation", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+
+	h.wg.Add(1)
+
+	go func() {
+		defer h.wg.Done()
+
+		ctx := logger.WithContext(context.Background())
+
+		if err := h.handleEvent(ctx, obj.namespace, objData, &payload); err != nil {
+			logger.Error().Err(err).Msg("failed to handle event")
+		}
+	}()
+}
+
+// Wait blocks until every delivery being processed is done.
+func (h *WebhookHandler) Wait() {
+	h.wg.Wait()
+}
+
+func (h *WebhookHandler) verifySignature(signature string, body []byte) error {
+	if len(h.Secret) == 0 {
+		return errors.New("webhook secret isn't configured")
+	}
+
+	hexMAC := strings.TrimPrefix(signature, "sha256=")
+	if hexMAC == signature {
+		return errInvalidSignature
+	}
+
+	expectedMAC, err := hex.DecodeString(hexMAC)
+	if err != nil {
+		return errInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, h.Secret)
+	mac.Write(body)
+
+	if !hmac.Equal(mac.Sum(nil), expectedMAC) {
+		return errInvalidSignature
+	}
+
+	return nil
+}
+
+func (h *WebhookHandler) handleEvent(ctx context.Context, namespace provider.Namespace, data map[string]any, payload *webhookPayload) error {
+	logger := zerolog.Ctx(ctx)
+
+	if !h.Reposaur.HasNamespace(string(namespace)) {
+		logger.Debug().Str("namespace", string(namespace)).Msg("no policies for event, skipping")
+		return nil
+	}
+
+	client, err := h.NewClient(ctx, payload.Installation.ID)
+	if err != nil {
+		return fmt.Errorf("create installation client: %w", err)
+	}
+
+	ctx = githubclient.NewContext(ctx, client)
+
+	defer cmdutil.LogGitHubRateLimits(logger, client)
+
+	report, err := h.Reposaur.CheckNamespace(ctx, string(namespace), data)
+	if err != nil {
+		return fmt.Errorf("check: %w", err)
+	}
+
+	if payload.Repository == nil {
+		logger.Info().
+			Bool("failed", report.HasFailures(h.FailOn)).
+			Msg("checked event without a repository, not publishing results")
+		return nil
+	}
+
+	sha, err := headSHA(ctx, client, payload)
+	if err != nil {
+		return fmt.Errorf("get head commit: %w", err)
+	}
+
+	repo := payload.Repository.FullName
+
+	switch h.Publish {
+	case PublishStatus:
+		err = publishStatus(ctx, client, repo, sha, report, h.FailOn)
+	default:
+		err = publishCheckRun(ctx, client, repo, sha, report, h.FailOn)
+	}
+
+	if err != nil {
+		return fmt.Errorf("publish results: %w", err)
+	}
+
+	logger.Info().Str("repository", repo).Str("sha", sha).Msg("published results")
+
+	return nil
+}
+
+// headSHA returns the head commit of the event, falling back to the
+// head of the repository default branch.
+func headSHA(ctx context.Context, client *githubclient.Client, payload *webhookPayload) (string, error) {
+	if payload.PullRequest != nil && payload.PullRequest.Head.SHA != "" {
+		return payload.PullRequest.Head.SHA, nil
+	}
+
+	if payload.After != "" {
+		return payload.After, nil
+	}
+
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+
+	path := fmt.Sprintf("/repos/%s/commits/%s", payload.Repository.FullName, url.PathEscape(payload.Repository.DefaultBranch))
+
+	if _, err := client.GetJSON(ctx, path, &commit); err != nil {
+		return "", err
+	}
+
+	return commit.SHA, nil
+}
+
+func publishCheckRun(ctx context.Context, client *githubclient.Client, repo, sha string, report output.Report, failOn string) error {
+	conclusion := "success"
+	if report.HasFailures(failOn) || report.HasErrors() {
+		conclusion = "failure"
+	}
+
+	title, summary := summarize(report)
+
+	return postJSON(ctx, client, fmt.Sprintf("/repos/%s/check-runs", repo), map[string]any{
+		"name":       "Reposaur",
+		"head_sha":   sha,
+		"status":     "completed",
+		"conclusion": conclusion,
+		"output": map[string]any{
+			"title":   title,
+			"summary": summary,
+		},
+	})
+}
+
+func publishStatus(ctx context.Context, client *githubclient.Client, repo, sha string, report output.Report, failOn string) error {
+	state := "success"
+
+	switch {
+	case report.HasFailures(failOn):
+		state = "failure"
+	case report.HasErrors():
+		state = "error"
+	}
+
+	title, _ := summarize(report)
+
+	return postJSON(ctx, client, fmt.Sprintf("/repos/%s/statuses/%s", repo, sha), map[string]any{
+		"state":       state,
+		"context":     "reposaur",
+		"description": title,
+	})
+}
+
+// summarize returns a title and a markdown summary of the failed results
+// and the rules that couldn't be evaluated.
+func summarize(report output.Report) (string, string) {
+	var (
+		failed, errored int
+		lines           []string
+	)
+
+	for _, r := range report.Results {
+		switch {
+		case r.Errored():
+			errored++
+			lines = append(lines, fmt.Sprintf("- **ERROR** `%s` couldn't be evaluated: %s", r.Rule.UID(), r.Error))
+
+		case r.Failed():
+			failed++
+			lines = append(lines, fmt.Sprintf("- **%s** `%s`: %s", strings.ToUpper(r.Rule.Severity), r.Rule.UID(), r.Text()))
+		}
+	}
+
+	if failed == 0 && errored == 0 {
+		return "No failed results", "Every policy passed."
+	}
+
+	title := fmt.Sprintf("%d failed results", failed)
+	if errored > 0 {
+		title += fmt.Sprintf(", %d errors", errored)
+	}
+
+	return title, strings.Join(lines, "\n")
+}
+
+func postJSON(ctx context.Context, client *githubclient.Client, path string, body any) error {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(body); err != nil {
+		return err
+	}
+
+	req, err := client.NewRequest(http.MethodPost, path, buf)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("post %s: unexpected status %d", path, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/reposaur/reposaur/pkg/output"
+	"github.com/reposaur/reposaur/pkg/sdk"
+	"github.com/reposaur/reposaur/provider/github"
+	githubclient "github.com/reposaur/reposaur/provider/github/client"
+	"github.com/rs/zerolog"
+)
+
+const testSecret = "It's a Secret to Everybody"
+
+type fakeGitHub struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	published []map[string]any
+	paths     []string
+}
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	t.Helper()
+
+	fake := &fakeGitHub{}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/repos/acme/api/commits/main", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"sha": "6dcb09b5b57875f334f61aebed695e2e4193db5e"}`))
+	})
+
+	mux.HandleFunc("/repos/acme/api/branches/main/protection", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token installation" {
+			t.Error("expected builtin to use the installation client")
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Branch not protected"}`))
+	})
+
+	publish := func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+
+		fake.mu.Lock()
+		fake.published = append(fake.published, body)
+		fake.paths = append(fake.paths, r.URL.Path)
+		fake.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{}`))
+	}
+
+	mux.HandleFunc("/repos/acme/api/check-runs", publish)
+	mux.HandleFunc("/repos/acme/api/statuses/", publish)
+
+	fake.Server = httptest.NewServer(mux)
+	t.Cleanup(fake.Close)
+
+	return fake
+}
+
+type tokenTransport struct{}
+
+func (tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token installation")
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestHandler(t *testing.T, fake *fakeGitHub, publish string) *WebhookHandler {
+	t.Helper()
+
+	ctx := context.Background()
+
+	rsr, err := sdk.New(ctx, []string{"testdata/policy"}, sdk.WithProvider(github.NewProvider(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &WebhookHandler{
+		Secret:   []byte(testSecret),
+		Reposaur: rsr,
+		NewClient: func(_ context.Context, installationID int64) (*githubclient.Client, error) {
+			if installationID != 234 {
+				t.Errorf("expected installation 234 got %d", installationID)
+			}
+
+			c := githubclient.NewClient(&http.Client{Transport: tokenTransport{}})
+			c.BaseURL, _ = url.Parse(fake.URL)
+
+			return c, nil
+		},
+		Publish: publish,
+		FailOn:  output.ErrorSeverity,
+		Logger:  zerolog.Nop(),
+	}
+}
+
+func newDelivery(t *testing.T, event, payloadFile, secret string) *http.Request {
+	t.Helper()
+
+	payload, err := os.ReadFile(payloadFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	return req
+}
+
+func TestWebhookRejectsInvalidSignature(t *testing.T) {
+	var (
+		fake    = newFakeGitHub(t)
+		handler = newTestHandler(t, fake, PublishCheckRun)
+		rec     = httptest.NewRecorder()
+	)
+
+	handler.ServeHTTP(rec, newDelivery(t, "pull_request", "testdata/pull_request.json", "wrong secret"))
+	handler.Wait()
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	if len(fake.published) != 0 {
+		t.Fatal("expected nothing to be published")
+	}
+}
+
+func TestWebhookPullRequestCheckRun(t *testing.T) {
+	var (
+		fake    = newFakeGitHub(t)
+		handler = newTestHandler(t, fake, PublishCheckRun)
+		rec     = httptest.NewRecorder()
+	)
+
+	handler.ServeHTTP(rec, newDelivery(t, "pull_request", "testdata/pull_request.json", testSecret))
+	handler.Wait()
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d got %d", http.StatusAccepted, rec.Code)
+	}
+
+	if len(fake.published) != 1 {
+		t.Fatalf("expected 1 check run got %d", len(fake.published))
+	}
+
+	checkRun := fake.published[0]
+
+	if checkRun["head_sha"] != "ec26c3e57ca3a959ca5aad62de7213c562f8c821" {
+		t.Errorf("expected check run on the pull request head, got %v", checkRun["head_sha"])
+	}
+
+	if checkRun["conclusion"] != "failure" {
+		t.Errorf("expected check run conclusion to be failure got %v", checkRun["conclusion"])
+	}
+}
+
+func TestWebhookRepositoryStatus(t *testing.T) {
+	var (
+		fake    = newFakeGitHub(t)
+		handler = newTestHandler(t, fake, PublishStatus)
+		rec     = httptest.NewRecorder()
+	)
+
+	handler.ServeHTTP(rec, newDelivery(t, "repository", "testdata/repository.json", testSecret))
+	handler.Wait()
+
+	if len(fake.published) != 1 {
+		t.Fatalf("expected 1 status got %d", len(fake.published))
+	}
+
+	if fake.paths[0] != "/repos/acme/api/statuses/6dcb09b5b57875f334f61aebed695e2e4193db5e" {
+		t.Errorf("expected status on the default branch head, got %s", fake.paths[0])
+	}
+
+	if fake.published[0]["state"] != "failure" {
+		t.Errorf("expected status state to be failure got %v", fake.published[0]["state"])
+	}
+}
+
+func TestWebhookIgnoresUnsupportedEvents(t *testing.T) {
+	var (
+		fake    = newFakeGitHub(t)
+		handler = newTestHandler(t, fake, PublishCheckRun)
+		rec     = httptest.NewRecorder()
+	)
+
+	handler.ServeHTTP(rec, newDelivery(t, "star", "testdata/repository.json", testSecret))
+	handler.Wait()
+
+	if rec.Code != http.StatusAccepted || len(fake.published) != 0 {
+		t.Fatalf("expected event to be ignored, got status %d and %d published", rec.Code, len(fake.published))
+	}
+}
+
+func TestWebhookIgnoresDeletedRefs(t *testing.T) {
+	var (
+		fake    = newFakeGitHub(t)
+		handler = newTestHandler(t, fake, PublishCheckRun)
+		rec     = httptest.NewRecorder()
+	)
+
+	handler.ServeHTTP(rec, newDelivery(t, "push", "testdata/push_deleted.json", testSecret))
+	handler.Wait()
+
+	if rec.Code != http.StatusAccepted || len(fake.published) != 0 {
+		t.Fatalf("expected push to be ignored, got status %d and %d published", rec.Code, len(fake.published))
+	}
+}
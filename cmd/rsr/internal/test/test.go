@@ -2,19 +2,31 @@ package test
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
+	"github.com/reposaur/reposaur/pkg/output"
 	"github.com/reposaur/reposaur/pkg/sdk"
+	"github.com/reposaur/reposaur/provider/gitea"
+	"github.com/reposaur/reposaur/provider/github"
+	githubclient "github.com/reposaur/reposaur/provider/github/client"
+	"github.com/reposaur/reposaur/provider/gitlab"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 )
 
 type testParams struct {
 	policyPaths    []string
+	dataPaths      []string
 	outputFilename string
+	format         string
 	enableTracing  bool
+	bundle         cmdutil.BundleOptions
+	github         cmdutil.GitHubClientOptions
+	gitlab         cmdutil.GitLabClientOptions
+	gitea          cmdutil.GiteaClientOptions
 }
 
 func NewCmd() *cobra.Command {
@@ -30,7 +42,14 @@ func NewCmd() *cobra.Command {
 	)
 
 	cmdutil.AddOutputFlag(flags, &params.outputFilename)
+	cmdutil.AddDataPathsFlag(flags, &params.dataPaths)
+	cmdutil.AddTestFormatFlag(flags, &params.format)
 	cmdutil.AddTraceFlag(flags, &params.enableTracing)
+	cmdutil.AddBundleFlags(flags, &params.bundle)
+	cmdutil.AddGitHubFlags(flags, &params.github)
+	cmdutil.AddFixtureFlags(flags, &params.github)
+	cmdutil.AddGitLabFlags(flags, &params.gitlab)
+	cmdutil.AddGiteaFlags(flags, &params.gitea)
 
 	cmd.Run = func(cmd *cobra.Command, args []string) {
 		var (
@@ -38,32 +57,73 @@ func NewCmd() *cobra.Command {
 			logger = zerolog.Ctx(ctx)
 		)
 
-		opts := []sdk.Option{
-			sdk.WithLogger(*logger),
-			sdk.WithTracingEnabled(params.enableTracing),
-		}
-
 		if len(args) > 0 {
 			params.policyPaths = args
 		}
 
-		rsr, err := sdk.New(ctx, params.policyPaths, opts...)
+		if params.format != "text" && params.format != string(output.JUnitFormat) {
+			logger.Fatal().Msgf("invalid --format '%s', expected text or junit", params.format)
+		}
+
+		githubClient, err := cmdutil.NewGitHubClient(ctx, &params.github)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create GitHub client")
+		}
+
+		rsr, err := newSDK(ctx, params, githubClient)
 		if err != nil {
 			logger.Fatal().Err(err).Msg("could not instantiate SDK")
 		}
 
-		runTest(ctx, rsr)
+		code := runTest(ctx, rsr, params)
+
+		if err := cmdutil.SaveGitHubFixtures(githubClient); err != nil {
+			logger.Fatal().Err(err).Msg("failed to save fixtures")
+		}
+
+		os.Exit(code)
 	}
 
 	return cmd
 }
 
-// runTest executes policy tests, outputting the results
-// to the provided  outWriter.
+// newSDK returns the SDK that runs the tests at params.policyPaths, with
+// every provider registered so their builtins can be called or mocked.
+func newSDK(ctx context.Context, params *testParams, githubClient *githubclient.Client) (*sdk.Reposaur, error) {
+	gitlabClient, err := cmdutil.NewGitLabClient(ctx, &params.gitlab)
+	if err != nil {
+		return nil, fmt.Errorf("create GitLab client: %w", err)
+	}
+
+	giteaClient, err := cmdutil.NewGiteaClient(ctx, &params.gitea)
+	if err != nil {
+		return nil, fmt.Errorf("create Gitea client: %w", err)
+	}
+
+	opts := []sdk.Option{
+		sdk.WithLogger(*zerolog.Ctx(ctx)),
+		sdk.WithProvider(github.NewProvider(githubClient, github.WithMaxPages(params.github.MaxPages))),
+		sdk.WithProvider(gitlab.NewProvider(gitlabClient)),
+		sdk.WithProvider(gitea.NewProvider(giteaClient)),
+		sdk.WithTracingEnabled(params.enableTracing),
+		sdk.WithData(params.dataPaths),
+	}
+
+	bundleOpts, err := cmdutil.BundleSDKOptions(&params.bundle)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle options: %w", err)
+	}
+
+	return sdk.New(ctx, params.policyPaths, append(opts, bundleOpts...)...)
+}
+
+// runTest executes policy tests, logging the results. If
+// params.format is junit, results are also written as JUnit XML
+// to params.outputFilename.
 //
-// If any test fails, the function will exit with code 1.
-// Otherwise, exits with code 0.
-func runTest(ctx context.Context, rsr *sdk.Reposaur) {
+// If any test fails or errors, returns cmdutil.ExitError. Otherwise,
+// returns cmdutil.ExitOK.
+func runTest(ctx context.Context, rsr *sdk.Reposaur, params *testParams) int {
 	var (
 		startTime = time.Now()
 		logger    = zerolog.Ctx(ctx)
@@ -74,14 +134,29 @@ func runTest(ctx context.Context, rsr *sdk.Reposaur) {
 		logger.Fatal().Err(err).Msg("failed to execute tests")
 	}
 
+	if params.format == string(output.JUnitFormat) {
+		outWriter, err := cmdutil.GetOutputWriter(ctx, params.outputFilename)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to get output writer")
+		}
+
+		if err := output.NewJUnitTestReport(results).Encode(outWriter); err != nil {
+			logger.Fatal().Err(err).Msg("failed to write JUnit report")
+		}
+
+		if err := outWriter.Close(); err != nil {
+			logger.Fatal().Err(err).Msg("failed to close output writer")
+		}
+	}
+
 	var failedTests, totalTests int
 
 	for _, r := range results {
 		totalTests++
 
-		if r.Fail {
+		if r.Fail || r.Error != nil {
 			failedTests++
-			logger.Error().Msg(r.String())
+			logger.Error().Err(r.Error).Msg(r.String())
 		} else {
 			logger.Info().Msg(r.String())
 		}
@@ -96,9 +171,9 @@ func runTest(ctx context.Context, rsr *sdk.Reposaur) {
 
 	if failedTests > 0 {
 		testLogger.Error().Msg("done")
-		os.Exit(1)
+		return cmdutil.ExitError
 	}
 
 	testLogger.Info().Msg("done")
-	os.Exit(0)
+	return cmdutil.ExitOK
 }
@@ -0,0 +1,28 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/reposaur/reposaur/cmd/rsr/internal/cmdutil"
+	gitlabclient "github.com/reposaur/reposaur/provider/gitlab/client"
+)
+
+func TestRunTestGitLabMocks(t *testing.T) {
+	ctx := context.Background()
+
+	params := &testParams{
+		policyPaths: []string{"testdata/gitlab"},
+		format:      "text",
+		gitlab:      cmdutil.GitLabClientOptions{BaseURL: gitlabclient.DefaultBaseURL},
+	}
+
+	rsr, err := newSDK(ctx, params, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runTest(ctx, rsr, params); code != cmdutil.ExitOK {
+		t.Fatalf("expected exit code %d got %d", cmdutil.ExitOK, code)
+	}
+}
@@ -0,0 +1,5 @@
+package reposaur
+
+mocks["GET /projects/{id}/repository/branches"] := {"body": [{"name": "main"}]}
+
+mocks["GET /projects/reposaur/legacy/repository/branches"] := {"body": [{"name": "master"}]}
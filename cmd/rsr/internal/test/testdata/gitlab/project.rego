@@ -0,0 +1,9 @@
+package gitlab.project
+
+import future.keywords.in
+
+branches := gitlab.request("GET /projects/{id}/repository/branches", {"id": input.path_with_namespace})
+
+violation_missing_main_branch {
+	not "main" in {b.name | some b in branches.body}
+}
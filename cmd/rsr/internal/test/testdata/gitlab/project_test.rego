@@ -0,0 +1,9 @@
+package gitlab.project
+
+test_main_branch {
+	not violation_missing_main_branch with input as {"path_with_namespace": "reposaur/reposaur"}
+}
+
+test_missing_main_branch {
+	violation_missing_main_branch with input as {"path_with_namespace": "reposaur/legacy"}
+}
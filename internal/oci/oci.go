@@ -0,0 +1,598 @@
+// Package oci implements a minimal client of the OCI distribution API,
+// enough to push and pull policy bundles to and from registries.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ManifestMediaType is the media type of image manifests.
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+	// ConfigMediaType is the media type of the (empty) config of bundles.
+	ConfigMediaType = "application/vnd.oci.image.config.v1+json"
+
+	// BundleLayerMediaType is the media type of the layer with the bundle
+	// tarball. It's the same media type used by OPA.
+	BundleLayerMediaType = "application/vnd.oci.image.layer.v1.tar+gzip"
+
+	// Scheme is the prefix of references to bundles in registries.
+	Scheme = "oci://"
+
+	// MaxBlobSize is the maximum size of the manifests and blobs
+	// read from registries, e.g. bundles.
+	MaxBlobSize = 64 << 20
+
+	// DefaultTimeout is the timeout of requests sent by clients
+	// created with NewClient.
+	DefaultTimeout = 2 * time.Minute
+
+	defaultTag = "latest"
+)
+
+var defaultHTTPClient = &http.Client{Timeout: DefaultTimeout}
+
+// Descriptor describes a blob in a registry.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an image manifest.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// BundleLayer returns the descriptor of the bundle layer in m.
+func (m Manifest) BundleLayer() (Descriptor, error) {
+	for _, l := range m.Layers {
+		if l.MediaType != BundleLayerMediaType {
+			continue
+		}
+
+		if err := ValidateDigest(l.Digest); err != nil {
+			return Descriptor{}, fmt.Errorf("bundle layer: %w", err)
+		}
+
+		return l, nil
+	}
+
+	return Descriptor{}, fmt.Errorf("manifest has no layer with media type %s", BundleLayerMediaType)
+}
+
+// Reference is a reference to a manifest in a registry, in the
+// form oci://registry/repository[:tag][@digest].
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses s as a Reference. The oci:// prefix is optional.
+// If neither a tag or a digest are present, the tag defaults to latest.
+func ParseReference(s string) (Reference, error) {
+	var ref Reference
+
+	rest := strings.TrimPrefix(s, Scheme)
+
+	i := strings.Index(rest, "/")
+	if i <= 0 || i == len(rest)-1 {
+		return ref, fmt.Errorf("invalid reference '%s': expected registry/repository", s)
+	}
+
+	ref.Registry, rest = rest[:i], rest[i+1:]
+
+	if i := strings.Index(rest, "@"); i >= 0 {
+		ref.Digest, rest = rest[i+1:], rest[:i]
+
+		if err := ValidateDigest(ref.Digest); err != nil {
+			return ref, fmt.Errorf("invalid reference '%s': %w", s, err)
+		}
+	}
+
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		ref.Tag, rest = rest[i+1:], rest[:i]
+	}
+
+	if rest == "" {
+		return ref, fmt.Errorf("invalid reference '%s': empty repository", s)
+	}
+
+	ref.Repository = rest
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+
+	return ref, nil
+}
+
+// String returns the reference in the form oci://registry/repository[:tag][@digest].
+func (r Reference) String() string {
+	s := Scheme + r.Registry + "/" + r.Repository
+
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+
+	return s
+}
+
+// Digest returns the sha256 digest of b, in the form sha256:<hex>.
+func Digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ValidateDigest returns an error if digest isn't in the form sha256:<hex>.
+func ValidateDigest(digest string) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("unsupported digest '%s', expected sha256:<hex>", digest)
+	}
+
+	if b, err := hex.DecodeString(strings.TrimPrefix(digest, "sha256:")); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("invalid digest '%s'", digest)
+	}
+
+	return nil
+}
+
+// ReadLimited reads r until EOF, like io.ReadAll. Returns an
+// error if r has more than MaxBlobSize bytes.
+func ReadLimited(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, MaxBlobSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) > MaxBlobSize {
+		return nil, fmt.Errorf("exceeds maximum size of %d bytes", MaxBlobSize)
+	}
+
+	return b, nil
+}
+
+// Verify returns an error if the digest of b isn't digest.
+func Verify(b []byte, digest string) error {
+	if actual := Digest(b); actual != digest {
+		return fmt.Errorf("digest mismatch: expected %s got %s", digest, actual)
+	}
+
+	return nil
+}
+
+// Client sends requests to registries. Credentials are optional and used
+// both for basic authentication and to request bearer tokens.
+type Client struct {
+	HTTPClient *http.Client
+	Username   string
+	Password   string
+
+	mu    sync.Mutex
+	auths map[string]string
+}
+
+// NewClient returns a new Client with the given credentials.
+func NewClient(username, password string) *Client {
+	return &Client{
+		HTTPClient: defaultHTTPClient,
+		Username:   username,
+		Password:   password,
+	}
+}
+
+// FetchManifest returns the manifest referenced by ref and its raw
+// bytes. If ref has a digest, the manifest is verified against it.
+func (c *Client) FetchManifest(ctx context.Context, ref Reference) (*Manifest, []byte, error) {
+	target := ref.Digest
+	if target == "" {
+		target = ref.Tag
+	}
+
+	resp, err := c.do(ctx, ref, "pull", http.MethodGet, c.url(ref, "manifests", target), nil, http.Header{
+		"Accept": {ManifestMediaType},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+
+	b, err := readResponse(resp, http.StatusOK)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch manifest %s: %w", ref, err)
+	}
+
+	if ref.Digest != "" {
+		if err := Verify(b, ref.Digest); err != nil {
+			return nil, nil, fmt.Errorf("fetch manifest %s: %w", ref, err)
+		}
+	}
+
+	m, err := DecodeManifest(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch manifest %s: %w", ref, err)
+	}
+
+	return m, b, nil
+}
+
+// FetchBlob returns the blob with digest from the repository of ref,
+// verified against digest.
+func (c *Client) FetchBlob(ctx context.Context, ref Reference, digest string) ([]byte, error) {
+	resp, err := c.do(ctx, ref, "pull", http.MethodGet, c.url(ref, "blobs", digest), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch blob: %w", err)
+	}
+
+	b, err := readResponse(resp, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("fetch blob %s: %w", digest, err)
+	}
+
+	if err := Verify(b, digest); err != nil {
+		return nil, fmt.Errorf("fetch blob %s: %w", digest, err)
+	}
+
+	return b, nil
+}
+
+// Push uploads bundle to the repository of ref and tags its manifest with
+// the tag of ref. Returns the digest of the manifest.
+func (c *Client) Push(ctx context.Context, ref Reference, bundle []byte) (string, error) {
+	if ref.Tag == "" {
+		return "", fmt.Errorf("push %s: reference must have a tag", ref)
+	}
+
+	config := []byte("{}")
+
+	m := Manifest{
+		SchemaVersion: 2,
+		MediaType:     ManifestMediaType,
+		Config: Descriptor{
+			MediaType: ConfigMediaType,
+			Digest:    Digest(config),
+			Size:      int64(len(config)),
+		},
+		Layers: []Descriptor{{
+			MediaType: BundleLayerMediaType,
+			Digest:    Digest(bundle),
+			Size:      int64(len(bundle)),
+			Annotations: map[string]string{
+				"org.opencontainers.image.title": "bundle.tar.gz",
+			},
+		}},
+	}
+
+	for _, blob := range [][]byte{config, bundle} {
+		if err := c.pushBlob(ctx, ref, blob); err != nil {
+			return "", fmt.Errorf("push %s: %w", ref, err)
+		}
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("push %s: %w", ref, err)
+	}
+
+	resp, err := c.do(ctx, ref, "pull,push", http.MethodPut, c.url(ref, "manifests", ref.Tag), b, http.Header{
+		"Content-Type": {ManifestMediaType},
+	})
+	if err != nil {
+		return "", fmt.Errorf("push %s: %w", ref, err)
+	}
+
+	if _, err := readResponse(resp, http.StatusCreated); err != nil {
+		return "", fmt.Errorf("push %s: manifest: %w", ref, err)
+	}
+
+	return Digest(b), nil
+}
+
+// DecodeManifest decodes b as a manifest.
+func DecodeManifest(b []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+
+	if m.SchemaVersion != 2 {
+		return nil, fmt.Errorf("decode manifest: unsupported schema version %d", m.SchemaVersion)
+	}
+
+	return &m, nil
+}
+
+// pushBlob uploads blob to the repository of ref using a monolithic
+// upload, unless the repository already has it.
+func (c *Client) pushBlob(ctx context.Context, ref Reference, blob []byte) error {
+	digest := Digest(blob)
+
+	resp, err := c.do(ctx, ref, "pull,push", http.MethodHead, c.url(ref, "blobs", digest), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = c.do(ctx, ref, "pull,push", http.MethodPost, c.url(ref, "blobs", "uploads/"), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := readResponse(resp, http.StatusAccepted); err != nil {
+		return fmt.Errorf("start upload of %s: %w", digest, err)
+	}
+
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("upload %s: invalid location: %w", digest, err)
+	}
+
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	resp, err = c.do(ctx, ref, "pull,push", http.MethodPut, location.String(), blob, http.Header{
+		"Content-Type": {"application/octet-stream"},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := readResponse(resp, http.StatusCreated); err != nil {
+		return fmt.Errorf("upload %s: %w", digest, err)
+	}
+
+	return nil
+}
+
+// do sends a request, authenticating with the registry if it responds
+// with a challenge. The resulting Authorization header is reused in
+// subsequent requests to the same repository.
+func (c *Client) do(ctx context.Context, ref Reference, actions, method, u string, body []byte, header http.Header) (*http.Response, error) {
+	key := ref.Registry + "/" + ref.Repository + ":" + actions
+
+	c.mu.Lock()
+	auth := c.auths[key]
+	c.mu.Unlock()
+
+	resp, err := c.send(ctx, method, u, body, header, auth)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	resp.Body.Close()
+
+	auth, err = c.authenticate(ctx, resp.Header.Get("WWW-Authenticate"), ref, actions)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.auths == nil {
+		c.auths = map[string]string{}
+	}
+	c.auths[key] = auth
+	c.mu.Unlock()
+
+	return c.send(ctx, method, u, body, header, auth)
+}
+
+func (c *Client) send(ctx context.Context, method, u string, body []byte, header http.Header, auth string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	return c.httpClient().Do(req)
+}
+
+// authenticate returns the Authorization header that answers challenge.
+func (c *Client) authenticate(ctx context.Context, challenge string, ref Reference, actions string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.Username == "" {
+			return "", errors.New("registry requires credentials")
+		}
+
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(c.Username, c.Password)
+
+		return req.Header.Get("Authorization"), nil
+
+	case "bearer":
+		return c.token(ctx, params, ref, actions)
+
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge '%s'", challenge)
+	}
+}
+
+// token requests a bearer token from the realm of a challenge.
+func (c *Client) token(ctx context.Context, params map[string]string, ref Reference, actions string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid authentication realm '%s'", params["realm"])
+	}
+
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.Repository + ":" + actions
+	}
+
+	query := realm.Query()
+	query.Set("scope", scope)
+
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request token: %w", err)
+	}
+
+	b, err := readResponse(resp, http.StatusOK)
+	if err != nil {
+		return "", fmt.Errorf("request token: %w", err)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := json.Unmarshal(b, &token); err != nil {
+		return "", fmt.Errorf("decode token: %w", err)
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	if token.Token == "" {
+		return "", errors.New("registry returned an empty token")
+	}
+
+	return "Bearer " + token.Token, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+
+	return defaultHTTPClient
+}
+
+// url returns the URL of an API endpoint of the repository of ref. Local
+// registries are accessed using HTTP, any other using HTTPS.
+func (c *Client) url(ref Reference, kind, target string) string {
+	scheme := "https"
+	if IsLocalhost(ref.Registry) {
+		scheme = "http"
+	}
+
+	return fmt.Sprintf("%s://%s/v2/%s/%s/%s", scheme, ref.Registry, ref.Repository, kind, target)
+}
+
+// IsLocalhost reports whether host, optionally with a port, is
+// localhost or a loopback address.
+func IsLocalhost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+// parseChallenge parses a WWW-Authenticate header in the form
+// Scheme key="value",key="value".
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+
+	for _, p := range splitParams(rest) {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok {
+			continue
+		}
+
+		params[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+
+	return scheme, params
+}
+
+// splitParams splits s by commas outside of quotes.
+func splitParams(s string) []string {
+	var (
+		params []string
+		quoted bool
+		start  int
+	)
+
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(params, s[start:])
+}
+
+// readResponse reads and closes the body of resp, returning an error
+// if its status isn't expected.
+func readResponse(resp *http.Response, expected int) ([]byte, error) {
+	defer resp.Body.Close()
+
+	b, err := ReadLimited(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != expected {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(b))
+	}
+
+	return b, nil
+}
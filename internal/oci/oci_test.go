@@ -0,0 +1,96 @@
+package oci_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/reposaur/reposaur/internal/oci"
+	"github.com/reposaur/reposaur/internal/oci/ocitest"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := oci.Digest([]byte("bundle"))
+
+	tests := map[string]oci.Reference{
+		"oci://ghcr.io/reposaur/policies":            {Registry: "ghcr.io", Repository: "reposaur/policies", Tag: "latest"},
+		"oci://ghcr.io/reposaur/policies:v1":         {Registry: "ghcr.io", Repository: "reposaur/policies", Tag: "v1"},
+		"oci://localhost:5000/policies:v1@" + digest: {Registry: "localhost:5000", Repository: "policies", Tag: "v1", Digest: digest},
+		"localhost:5000/policies@" + digest:          {Registry: "localhost:5000", Repository: "policies", Digest: digest},
+	}
+
+	for s, expected := range tests {
+		ref, err := oci.ParseReference(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+
+		if ref != expected {
+			t.Fatalf("%s: expected %+v got %+v", s, expected, ref)
+		}
+	}
+
+	for _, s := range []string{"oci://ghcr.io", "oci://ghcr.io/", "oci://ghcr.io/policies@md5:abc", "oci://ghcr.io/policies@sha256:abc"} {
+		if _, err := oci.ParseReference(s); err == nil {
+			t.Fatalf("%s: expected an error", s)
+		}
+	}
+}
+
+func TestPushAndFetch(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		registry = ocitest.NewRegistry(t)
+		client   = oci.NewClient("", "")
+		bundle   = []byte("bundle contents")
+	)
+
+	ref, err := oci.ParseReference("oci://" + registry.Host() + "/reposaur/policies:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := client.Push(ctx, ref, bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pushing again doesn't upload the blobs the registry already has.
+	if _, err := client.Push(ctx, ref, bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	ref.Digest = digest
+
+	m, _, err := client.FetchManifest(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer, err := m.BundleLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := client.FetchBlob(ctx, ref, layer.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != string(bundle) {
+		t.Fatalf("expected bundle '%s' got '%s'", bundle, b)
+	}
+
+	// Blobs that don't match their digest are rejected.
+	registry.SetBlob(layer.Digest, []byte("tampered"))
+
+	if _, err := client.FetchBlob(ctx, ref, layer.Digest); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatalf("expected digest mismatch error got %v", err)
+	}
+
+	ref.Digest = oci.Digest([]byte("another manifest"))
+
+	if _, _, err := client.FetchManifest(ctx, ref); err == nil {
+		t.Fatal("expected an error fetching an unknown manifest")
+	}
+}
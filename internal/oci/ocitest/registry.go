@@ -0,0 +1,150 @@
+// Package ocitest provides an in-memory OCI registry for tests.
+package ocitest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/reposaur/reposaur/internal/oci"
+)
+
+// Token is the bearer token issued by a Registry.
+const Token = "ocitest-token"
+
+// Registry is an in-memory registry that implements the subset of
+// the distribution API used by oci.Client. Every request must be
+// authenticated with a bearer token obtained from the registry.
+type Registry struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	uploads   int
+}
+
+// NewRegistry starts a Registry that is closed when t finishes.
+func NewRegistry(t testing.TB) *Registry {
+	r := &Registry{
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+	}
+
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+// Host returns the host of the registry, to be used in references.
+func (r *Registry) Host() string {
+	return strings.TrimPrefix(r.URL, "http://")
+}
+
+// SetBlob stores b as the blob with digest, regardless of its contents.
+func (r *Registry) SetBlob(digest string, b []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.blobs[digest] = b
+}
+
+func (r *Registry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		fmt.Fprintf(w, `{"token":%q}`, Token)
+		return
+	}
+
+	if req.Header.Get("Authorization") != "Bearer "+Token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="ocitest"`, r.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case strings.Contains(path, "/blobs/uploads/"):
+		r.serveUpload(w, req)
+
+	case strings.Contains(path, "/blobs/"):
+		b, ok := r.blobs[path[strings.LastIndex(path, "/")+1:]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(b)
+		}
+
+	case strings.Contains(path, "/manifests/"):
+		r.serveManifest(w, req, path)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (r *Registry) serveUpload(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPost:
+		r.uploads++
+		w.Header().Set("Location", fmt.Sprintf("%s%d", req.URL.Path, r.uploads))
+		w.WriteHeader(http.StatusAccepted)
+
+	case http.MethodPut:
+		b, _ := io.ReadAll(req.Body)
+		digest := req.URL.Query().Get("digest")
+
+		if oci.Verify(b, digest) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		r.blobs[digest] = b
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Registry) serveManifest(w http.ResponseWriter, req *http.Request, path string) {
+	i := strings.Index(path, "/manifests/")
+	repo, target := path[:i], path[i+len("/manifests/"):]
+
+	switch req.Method {
+	case http.MethodPut:
+		b, _ := io.ReadAll(req.Body)
+		r.manifests[repo+":"+target] = b
+		r.manifests[repo+"@"+oci.Digest(b)] = b
+		w.WriteHeader(http.StatusCreated)
+
+	case http.MethodGet:
+		sep := ":"
+		if strings.HasPrefix(target, "sha256:") {
+			sep = "@"
+		}
+
+		b, ok := r.manifests[repo+sep+target]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", oci.ManifestMediaType)
+		_, _ = w.Write(b)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/loader"
+)
+
+// loadData loads the JSON and YAML documents at paths. Other
+// files in directories, e.g. policies, are ignored.
+func loadData(paths []string) (map[string]interface{}, error) {
+	result, err := loader.NewFileLoader().Filtered(paths, isDataFile)
+	if err != nil {
+		return nil, fmt.Errorf("data: %w", err)
+	}
+
+	return result.Documents, nil
+}
+
+// checkDataRoots returns an error if a document in docs would
+// replace any of the data owned by bundles with roots.
+func checkDataRoots(docs map[string]interface{}, roots []string) error {
+	return walkDataRoots(docs, "", roots)
+}
+
+func walkDataRoots(docs map[string]interface{}, prefix string, roots []string) error {
+	for key, value := range docs {
+		path := key
+		if prefix != "" {
+			path = prefix + "/" + key
+		}
+
+		if bundle.RootPathsContain(roots, path) {
+			return fmt.Errorf("data: %s conflicts with bundle roots %v", path, roots)
+		}
+
+		obj, ok := value.(map[string]interface{})
+
+		for _, root := range roots {
+			if !strings.HasPrefix(root, path+"/") {
+				continue
+			}
+
+			// The document contains a root, it can only
+			// be merged if it's an object.
+			if !ok {
+				return fmt.Errorf("data: %s conflicts with bundle roots %v", path, roots)
+			}
+		}
+
+		if ok {
+			if err := walkDataRoots(obj, path, roots); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeData merges the documents in src into dst. Objects are merged
+// recursively. Returns an error if any other value would be replaced.
+func mergeData(dst, src map[string]interface{}) error {
+	return mergeDataAt(dst, src, "")
+}
+
+func mergeDataAt(dst, src map[string]interface{}, prefix string) error {
+	for key, value := range src {
+		path := key
+		if prefix != "" {
+			path = prefix + "/" + key
+		}
+
+		existing, ok := dst[key]
+		if !ok {
+			dst[key] = value
+			continue
+		}
+
+		existingObj, ok1 := existing.(map[string]interface{})
+		valueObj, ok2 := value.(map[string]interface{})
+
+		if !ok1 || !ok2 {
+			return fmt.Errorf("data: %s is defined more than once", path)
+		}
+
+		if err := mergeDataAt(existingObj, valueObj, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isDataFile filters out the files that aren't JSON or YAML documents.
+func isDataFile(_ string, info os.FileInfo, _ int) bool {
+	if info.IsDir() {
+		return false
+	}
+
+	switch filepath.Ext(info.Name()) {
+	case ".json", ".yaml", ".yml":
+		return false
+	}
+
+	return true
+}
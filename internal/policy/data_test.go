@@ -0,0 +1,49 @@
+package policy_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/storage"
+
+	"github.com/reposaur/reposaur/internal/policy"
+)
+
+func TestLoadData(t *testing.T) {
+	ctx := context.Background()
+
+	engine, err := policy.Load(ctx, []string{"testdata/bundle"}, policy.WithData([]string{"testdata/data"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]interface{}{
+		// Documents are loaded under the path of their directory.
+		"/allowed_licenses": []interface{}{"mit", "apache-2.0"},
+		"/teams/admins":     []interface{}{"octocat"},
+
+		// The data of bundles is kept.
+		"/reposaur/config/max_admins": json.Number("2"),
+	}
+
+	for path, expected := range tests {
+		value, err := storage.ReadOne(ctx, engine.Store(), storage.MustParsePath(path))
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+
+		if !reflect.DeepEqual(value, expected) {
+			t.Fatalf("%s: expected %v got %v", path, expected, value)
+		}
+	}
+}
+
+func TestLoadDataRootsConflict(t *testing.T) {
+	_, err := policy.Load(context.Background(), []string{"testdata/bundle"}, policy.WithData([]string{"testdata/dataconflict"}))
+	if err == nil || !strings.Contains(err.Error(), "conflicts with bundle roots") {
+		t.Fatalf("expected roots conflict error got %v", err)
+	}
+}
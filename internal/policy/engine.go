@@ -2,39 +2,200 @@ package policy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/bundle"
 	"github.com/open-policy-agent/opa/loader"
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
 	"github.com/open-policy-agent/opa/topdown"
+	"github.com/reposaur/reposaur/internal/oci"
 	"github.com/reposaur/reposaur/pkg/output"
 )
 
 type Option func(*Engine)
 
 type Engine struct {
-	modules       map[string]*ast.Module
-	compiler      *ast.Compiler
-	enableTracing bool
+	paths     []string
+	modules   map[string]*ast.Module
+	compiler  *ast.Compiler
+	store     storage.Store
+	manifests map[string]bundle.Manifest
+
+	// annotations are the rule annotations in the metadata of
+	// bundles by rule path, used for rules without METADATA
+	// comments, e.g. those of optimized bundles.
+	annotations map[string]*ast.Annotations
+
+	dataPaths      []string
+	bundleCacheDir string
+	httpClient     *http.Client
+	ociClient      *oci.Client
+	verification   *bundle.VerificationConfig
+	skipVerify     bool
+	enableTracing  bool
+	ruleTimeout    time.Duration
+	inputTimeout   time.Duration
+	concurrency    int
+
+	// prepared caches the prepared queries by query string, so that
+	// each query is prepared once and reused across inputs. The mutex
+	// only guards the map, queries are prepared concurrently.
+	preparedMu sync.Mutex
+	prepared   map[string]*preparedQuery
+
+	// traceMu serializes the printing of traces of rules
+	// evaluated concurrently.
+	traceMu sync.Mutex
 }
 
-func Load(_ context.Context, policyPaths []string, opts ...Option) (*Engine, error) {
-	policies, err := loader.NewFileLoader().
-		WithProcessAnnotation(true).
-		Filtered(policyPaths, isRegoFile)
+// Load loads and compiles the policies at policyPaths. Paths can be
+// directories or files with .rego policies, or bundles: either .tar.gz
+// files or directories with a .manifest file. The data documents of
+// bundles, and of any paths passed to WithData, are loaded into the
+// engine's store.
+//
+// Bundles can also be fetched from HTTP(S) URLs, optionally pinned with
+// a #sha256=<hex> fragment, or from OCI registries with references in
+// the form oci://registry/repository[:tag][@sha256:<hex>]. They're
+// downloaded into a cache and verified against the pinned digest
+// before being loaded. Bundles fetched over plain HTTP must be pinned,
+// unless they're served from localhost.
+func Load(ctx context.Context, policyPaths []string, opts ...Option) (*Engine, error) {
+	engine := &Engine{
+		concurrency: runtime.NumCPU(),
+		prepared:    map[string]*preparedQuery{},
+		httpClient:  &http.Client{Timeout: oci.DefaultTimeout},
+		ociClient:   oci.NewClient("", ""),
+	}
+
+	for _, opt := range opts {
+		opt(engine)
+	}
+
+	var (
+		localPaths  []string
+		regoPaths   []string
+		bundlePaths []string
+		bundleRefs  []string
+	)
+
+	for _, path := range policyPaths {
+		local := path
+
+		switch {
+		case isRemote(path):
+			var err error
+			if local, err = engine.fetchBundle(ctx, path); err != nil {
+				return nil, &ErrPolicyLoad{err}
+			}
+
+			bundlePaths = append(bundlePaths, local)
+			bundleRefs = append(bundleRefs, path)
+
+		case isBundle(path):
+			bundlePaths = append(bundlePaths, path)
+			bundleRefs = append(bundleRefs, path)
+
+		default:
+			regoPaths = append(regoPaths, path)
+		}
+
+		localPaths = append(localPaths, local)
+	}
+
+	modules := map[string]*ast.Module{}
+
+	// Policies that aren't bundles can't be verified.
+	if engine.verifying() && len(regoPaths) > 0 {
+		return nil, &ErrPolicyLoad{fmt.Errorf("policies at %v aren't signed bundles and can't be verified", regoPaths)}
+	}
+
+	if len(regoPaths) > 0 {
+		policies, err := loader.NewFileLoader().
+			WithProcessAnnotation(true).
+			Filtered(regoPaths, isRegoFile)
+		if err != nil {
+			return nil, &ErrPolicyLoad{err}
+		}
+
+		modules = policies.ParsedModules()
+	}
+
+	bundles, err := engine.loadBundles(bundlePaths, bundleRefs)
 	if err != nil {
 		return nil, &ErrPolicyLoad{err}
 	}
 
-	if len(policies.Modules) == 0 {
+	var (
+		data        = map[string]interface{}{}
+		manifests   = map[string]bundle.Manifest{}
+		annotations = map[string]*ast.Annotations{}
+		roots       []string
+	)
+
+	if len(bundles) > 0 {
+		merged, err := bundle.Merge(bundles)
+		if err != nil {
+			return nil, &ErrPolicyLoad{err}
+		}
+
+		if err := checkBundleRoots(modules, *merged.Manifest.Roots); err != nil {
+			return nil, &ErrPolicyLoad{err}
+		}
+
+		for i, b := range bundles {
+			manifests[bundleRefs[i]] = b.Manifest
+
+			// Metadata was already validated when loading the bundle.
+			if md, _ := ManifestMetadata(b.Manifest); md != nil {
+				for path, a := range md.Annotations {
+					annotations[path] = a.annotations()
+				}
+			}
+
+			for path, mod := range b.ParsedModules(bundleRefs[i]) {
+				modules[path] = mod
+			}
+		}
+
+		if merged.Data != nil {
+			data = merged.Data
+		}
+
+		roots = *merged.Manifest.Roots
+	}
+
+	if len(engine.dataPaths) > 0 {
+		docs, err := loadData(engine.dataPaths)
+		if err != nil {
+			return nil, &ErrPolicyLoad{err}
+		}
+
+		if err := checkDataRoots(docs, roots); err != nil {
+			return nil, &ErrPolicyLoad{err}
+		}
+
+		if err := mergeData(data, docs); err != nil {
+			return nil, &ErrPolicyLoad{err}
+		}
+	}
+
+	if len(modules) == 0 {
 		return nil, &ErrNoPolicies{policyPaths}
 	}
 
-	modules := policies.ParsedModules()
 	compiler := ast.NewCompiler().WithEnablePrintStatements(true)
 
 	compiler.Compile(modules)
@@ -43,14 +204,12 @@ func Load(_ context.Context, policyPaths []string, opts ...Option) (*Engine, err
 		return nil, fmt.Errorf("compiler: %w", compiler.Errors)
 	}
 
-	engine := &Engine{
-		modules:  modules,
-		compiler: compiler,
-	}
-
-	for _, opt := range opts {
-		opt(engine)
-	}
+	engine.paths = localPaths
+	engine.modules = modules
+	engine.compiler = compiler
+	engine.store = inmem.NewFromObject(data)
+	engine.manifests = manifests
+	engine.annotations = annotations
 
 	return engine, nil
 }
@@ -63,33 +222,151 @@ func WithTracingEnabled(enabled bool) Option {
 	}
 }
 
-// Namespaces returns all the namespaces in the engine.
+// WithRuleTimeout sets the maximum duration of a rule evaluation.
+// Rules that time out produce an error result. Zero means no timeout.
+func WithRuleTimeout(d time.Duration) Option {
+	return func(e *Engine) {
+		e.ruleTimeout = d
+	}
+}
+
+// WithInputTimeout sets the maximum duration of the evaluation of every
+// rule against an input. Rules not evaluated in time produce an error
+// result. Zero means no timeout.
+func WithInputTimeout(d time.Duration) Option {
+	return func(e *Engine) {
+		e.inputTimeout = d
+	}
+}
+
+// WithConcurrency sets the maximum number of rules evaluated concurrently
+// against an input. Defaults to the number of CPUs. Values lower than 1
+// evaluate one rule at a time.
+func WithConcurrency(n int) Option {
+	return func(e *Engine) {
+		e.concurrency = n
+	}
+}
+
+// WithData loads the JSON and YAML documents at paths, either files
+// or directories, into the store. Documents in directories are loaded
+// under the path of their directory, e.g. the contents of
+// teams/data.json are available as data.teams.
+func WithData(paths []string) Option {
+	return func(e *Engine) {
+		e.dataPaths = paths
+	}
+}
+
+// WithBundleCacheDir sets the directory where remote bundles are cached.
+// Defaults to a reposaur/bundles directory in the user cache directory.
+func WithBundleCacheDir(dir string) Option {
+	return func(e *Engine) {
+		e.bundleCacheDir = dir
+	}
+}
+
+// WithOCIClient sets the client used to fetch bundles from OCI registries.
+func WithOCIClient(c *oci.Client) Option {
+	return func(e *Engine) {
+		e.ociClient = c
+	}
+}
+
+// WithBundleVerification sets the keys used to verify the signatures of
+// bundles. If set, unsigned bundles and policies that aren't bundles
+// are refused.
+func WithBundleVerification(config *bundle.VerificationConfig) Option {
+	return func(e *Engine) {
+		e.verification = config
+	}
+}
+
+// WithSkipBundleVerification disables the verification of the
+// signatures of bundles.
+func WithSkipBundleVerification(skip bool) Option {
+	return func(e *Engine) {
+		e.skipVerify = skip
+	}
+}
+
+// Namespaces returns all the namespaces in the engine, sorted.
 func (e *Engine) Namespaces() []string {
+	seen := map[string]bool{}
+
 	var namespaces []string
 	for _, module := range e.Modules() {
-		namespace := strings.Replace(module.Package.Path.String(), "data.", "", 1)
-		for _, ns := range namespaces {
-			if ns == namespace {
-				continue
-			}
+		namespace := moduleNamespace(module)
+		if seen[namespace] {
+			continue
 		}
 
+		seen[namespace] = true
 		namespaces = append(namespaces, namespace)
 	}
 
+	sort.Strings(namespaces)
+
 	return namespaces
 }
 
+// Rules returns the rules in every namespace. Returns an error listing
+// the rules that have a known kind but are invalid, e.g. because of
+// their annotations. See output.NewRule.
+func (e *Engine) Rules() ([]*output.Rule, error) {
+	var (
+		rules []*output.Rule
+		errs  []error
+	)
+
+	for _, mod := range e.Modules() {
+		modRules, modErrs := moduleRules(mod, e.annotations)
+
+		rules = append(rules, modRules...)
+		errs = append(errs, modErrs...)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].UID() < rules[j].UID()
+	})
+
+	if len(errs) > 0 {
+		return nil, &ErrInvalidRules{errs}
+	}
+
+	return rules, nil
+}
+
 // Compiler returns the compiler from the loaded policies.
 func (e *Engine) Compiler() *ast.Compiler {
 	return e.compiler
 }
 
+// Store returns the store with the data documents from the
+// loaded bundles.
+func (e *Engine) Store() storage.Store {
+	return e.store
+}
+
+// Paths returns the paths the policies were loaded from. Remote
+// bundles are replaced by the paths they were downloaded to.
+func (e *Engine) Paths() []string {
+	return e.paths
+}
+
+// Manifests returns the manifests of the loaded bundles by path.
+func (e *Engine) Manifests() map[string]bundle.Manifest {
+	return e.manifests
+}
+
 // Modules returns the modules from the loaded policies.
 func (e *Engine) Modules() map[string]*ast.Module {
 	return e.modules
 }
 
+// Check evaluates the rules in namespace against input. Rules that can't be
+// evaluated produce an error result instead of aborting the check, so an
+// error is only returned if ctx is cancelled.
 func (e *Engine) Check(ctx context.Context, namespace string, input interface{}) (output.Report, error) {
 	report, err := e.check(ctx, namespace, input)
 	if err != nil {
@@ -101,79 +378,173 @@ func (e *Engine) Check(ctx context.Context, namespace string, input interface{})
 
 func (e *Engine) check(ctx context.Context, namespace string, input interface{}) (output.Report, error) {
 	report := output.Report{
-		Rules:   map[string]*output.Rule{},
-		Results: map[string]*output.Result{},
+		Rules: map[string]*output.Rule{},
 	}
 
 	for _, mod := range e.Modules() {
-		currNamespace := strings.TrimPrefix(mod.Package.Path.String(), "data.")
-		if currNamespace != namespace {
+		if moduleNamespace(mod) != namespace {
 			continue
 		}
 
-		for _, r := range mod.Rules {
-			var annotations *ast.Annotations
-			for _, a := range mod.Annotations {
-				if a.Scope == "rule" && a.GetTargetPath().String() == r.Ref().String() {
-					annotations = a
-				}
-			}
-
-			rule, err := output.NewRule(namespace, r, annotations)
-			if err != nil {
-				continue
-			}
+		// Invalid rules are caught when bundling, see Rules.
+		rules, _ := moduleRules(mod, e.annotations)
 
+		for _, rule := range rules {
 			report.AddRule(rule)
 		}
 	}
 
+	inputCtx := ctx
+
+	if e.inputTimeout > 0 {
+		var cancel context.CancelFunc
+		inputCtx, cancel = context.WithTimeout(ctx, e.inputTimeout)
+		defer cancel()
+	}
+
+	rules := make([]*output.Rule, 0, len(report.Rules))
 	for _, rule := range report.Rules {
-		var result *output.Result
+		rules = append(rules, rule)
+	}
 
-		result, err := e.querySkip(ctx, rule, input)
-		if err != nil {
-			return output.Report{}, fmt.Errorf("query skip rule: %s: %w", rule.UID(), err)
-		}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].UID() < rules[j].UID()
+	})
+
+	// Each rule's results are stored at its index, so that results are
+	// reported in the same order regardless of the evaluation order.
+	var (
+		results = make([][]*output.Result, len(rules))
+		next    = make(chan int)
+		wg      sync.WaitGroup
+	)
+
+	workers := e.concurrency
+	if workers < 1 {
+		workers = 1
+	}
 
-		if !result.Skipped {
-			result, err = e.queryRule(ctx, rule, input)
-			if err != nil {
-				return output.Report{}, fmt.Errorf("query rule: %s: %w", rule.UID(), err)
+	if workers > len(rules) {
+		workers = len(rules)
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range next {
+				results[i] = e.evalRuleWithTimeout(ctx, inputCtx, rules[i], input)
 			}
+		}()
+	}
+
+	for i := range rules {
+		if ctx.Err() != nil {
+			break
 		}
 
-		report.AddResult(result)
+		next <- i
+	}
+
+	close(next)
+	wg.Wait()
+
+	// Cancellations by the caller abort the check, any other
+	// error is reported as a result so that the remaining
+	// rules are still evaluated.
+	if err := ctx.Err(); err != nil {
+		return output.Report{}, err
+	}
+
+	for _, rr := range results {
+		for _, r := range rr {
+			report.AddResult(r)
+		}
 	}
 
 	return report, nil
 }
 
-func (e *Engine) queryRule(ctx context.Context, rule *output.Rule, input interface{}) (*output.Result, error) {
-	query := fmt.Sprintf("data.%s.%s_%s", rule.Namespace, rule.Kind, rule.ID)
-	regoInstance := e.buildRegoInstance(query, input)
+// evalRuleWithTimeout evaluates rule against input, limited by the rule
+// timeout. If the evaluation fails, returns an error result.
+func (e *Engine) evalRuleWithTimeout(ctx, inputCtx context.Context, rule *output.Rule, input interface{}) []*output.Result {
+	ruleCtx, cancel := inputCtx, context.CancelFunc(func() {})
+	if e.ruleTimeout > 0 {
+		ruleCtx, cancel = context.WithTimeout(inputCtx, e.ruleTimeout)
+	}
+	defer cancel()
 
-	resultSet, err := regoInstance.Eval(ctx)
+	results, err := e.evalRule(ruleCtx, rule, input)
 	if err != nil {
-		return nil, fmt.Errorf("query eval: %w", err)
+		return []*output.Result{e.newErrorResult(rule, ruleCtx, inputCtx, err)}
 	}
 
-	result := output.Result{
-		Rule:   rule,
-		Query:  query,
-		Passed: len(resultSet) == 0,
+	return results
+}
+
+// evalRule evaluates rule against input, skipping it if needed.
+func (e *Engine) evalRule(ctx context.Context, rule *output.Rule, input interface{}) ([]*output.Result, error) {
+	result, err := e.querySkip(ctx, rule, input)
+	if err != nil {
+		return nil, err
 	}
 
-	return &result, nil
+	if result.Skipped {
+		return []*output.Result{result}, nil
+	}
+
+	return e.queryRule(ctx, rule, input)
+}
+
+// newErrorResult returns the result of a rule whose evaluation failed with
+// err. If the evaluation timed out, the error says which timeout expired.
+func (e *Engine) newErrorResult(rule *output.Rule, ruleCtx, inputCtx context.Context, err error) *output.Result {
+	result := &output.Result{
+		Rule:  rule,
+		Error: err.Error(),
+	}
+
+	var evalErr *ErrEval
+	if errors.As(err, &evalErr) {
+		result.Query = evalErr.Query
+		result.Error = evalErr.Err.Error()
+	}
+
+	switch {
+	case inputCtx.Err() == context.DeadlineExceeded:
+		result.Error = fmt.Sprintf("input evaluation timed out after %s", e.inputTimeout)
+
+	case ruleCtx.Err() == context.DeadlineExceeded:
+		result.Error = fmt.Sprintf("rule evaluation timed out after %s", e.ruleTimeout)
+	}
+
+	return result
+}
+
+// queryRule evaluates rule against input. Returns a single passed result if
+// the rule is undefined, false or an empty set. Otherwise, returns a failed
+// result for each value produced by the rule. See newRuleResults.
+func (e *Engine) queryRule(ctx context.Context, rule *output.Rule, input interface{}) ([]*output.Result, error) {
+	query := fmt.Sprintf("data.%s.%s_%s", rule.Namespace, rule.Kind, rule.ID)
+	resultSet, err := e.eval(ctx, query, input)
+	if err != nil {
+		return nil, &ErrEval{Query: query, Err: err}
+	}
+
+	if len(resultSet) == 0 || len(resultSet[0].Expressions) == 0 {
+		return []*output.Result{{Rule: rule, Query: query, Passed: true}}, nil
+	}
+
+	return newRuleResults(rule, query, resultSet[0].Expressions[0].Value), nil
 }
 
 func (e *Engine) querySkip(ctx context.Context, rule *output.Rule, input interface{}) (*output.Result, error) {
 	query := fmt.Sprintf("data.%s.skip[_][_] == %q", rule.Namespace, rule.ID)
-	regoInstance := e.buildRegoInstance(query, input)
-
-	resultSet, err := regoInstance.Eval(ctx)
+	resultSet, err := e.eval(ctx, query, input)
 	if err != nil {
-		return nil, fmt.Errorf("skip query eval: %w", err)
+		return nil, &ErrEval{Query: query, Err: err}
 	}
 
 	result := output.Result{
@@ -185,15 +556,205 @@ func (e *Engine) querySkip(ctx context.Context, rule *output.Rule, input interfa
 	return &result, nil
 }
 
-func (e *Engine) buildRegoInstance(query string, input interface{}) *rego.Rego {
-	return rego.New(
-		rego.Query(query),
-		rego.Input(input),
-		rego.Compiler(e.compiler),
-		rego.Trace(e.enableTracing),
-		rego.StrictBuiltinErrors(true),
-		rego.PrintHook(topdown.NewPrintHook(os.Stderr)),
+// eval evaluates query against input, preparing it if it
+// wasn't prepared yet.
+func (e *Engine) eval(ctx context.Context, query string, input interface{}) (rego.ResultSet, error) {
+	pq, err := e.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []rego.EvalOption{rego.EvalInput(input)}
+
+	// Prepared queries are shared between evaluations, so each
+	// evaluation gets its own tracer.
+	var tracer *topdown.BufferTracer
+	if e.enableTracing {
+		tracer = topdown.NewBufferTracer()
+		opts = append(opts, rego.EvalQueryTracer(tracer))
+	}
+
+	resultSet, err := pq.Eval(ctx, opts...)
+
+	if tracer != nil {
+		e.traceMu.Lock()
+		topdown.PrettyTrace(os.Stderr, *tracer)
+		e.traceMu.Unlock()
+	}
+
+	return resultSet, err
+}
+
+// preparedQuery is a query prepared once, shared by
+// every evaluation of the query.
+type preparedQuery struct {
+	once sync.Once
+	pq   rego.PreparedEvalQuery
+	err  error
+}
+
+// prepare returns the prepared query, preparing it only once. Callers
+// of the same query wait for it to be prepared, while other queries are
+// prepared concurrently. Queries that fail are prepared again on the
+// next call, e.g. if ctx was cancelled.
+func (e *Engine) prepare(ctx context.Context, query string) (*rego.PreparedEvalQuery, error) {
+	e.preparedMu.Lock()
+	p, ok := e.prepared[query]
+	if !ok {
+		p = &preparedQuery{}
+		e.prepared[query] = p
+	}
+	e.preparedMu.Unlock()
+
+	p.once.Do(func() {
+		p.pq, p.err = rego.New(
+			rego.Query(query),
+			rego.Compiler(e.compiler),
+			rego.Store(e.store),
+			rego.StrictBuiltinErrors(true),
+			rego.PrintHook(topdown.NewPrintHook(os.Stderr)),
+		).PrepareForEval(ctx)
+	})
+
+	if p.err != nil {
+		e.preparedMu.Lock()
+		if e.prepared[query] == p {
+			delete(e.prepared, query)
+		}
+		e.preparedMu.Unlock()
+
+		return nil, p.err
+	}
+
+	return &p.pq, nil
+}
+
+// moduleNamespace returns the namespace of mod, i.e.
+// its package path without the data prefix.
+func moduleNamespace(mod *ast.Module) string {
+	return strings.TrimPrefix(mod.Package.Path.String(), "data.")
+}
+
+// moduleRules returns the rules in mod, ignoring helper rules. Rules
+// defined more than once (e.g. partial set rules) are only returned once.
+// Rules without annotations use those in fallback, by rule path, if any.
+// Returns an error for each invalid rule.
+func moduleRules(mod *ast.Module, fallback map[string]*ast.Annotations) ([]*output.Rule, []error) {
+	var (
+		rules     []*output.Rule
+		errs      []error
+		seen      = map[string]bool{}
+		namespace = moduleNamespace(mod)
 	)
+
+	for _, r := range mod.Rules {
+		name := r.Head.Name.String()
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+
+		var annotations *ast.Annotations
+		for _, a := range mod.Annotations {
+			if a.Scope == "rule" && a.GetTargetPath().Equal(r.Path()) {
+				annotations = a
+			}
+		}
+
+		if annotations == nil {
+			annotations = fallback[r.Path().String()]
+		}
+
+		rule, err := output.NewRule(namespace, r, annotations)
+		if errors.Is(err, output.ErrNotRule) {
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Location, err))
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, errs
+}
+
+// loadBundles loads the bundles at paths, checking that their
+// modules and data are within the roots of their manifests. If a
+// verification config is set, bundles must be signed and their
+// signatures valid. Errors are reported with the matching refs.
+func (e *Engine) loadBundles(paths, refs []string) ([]*bundle.Bundle, error) {
+	bundles := make([]*bundle.Bundle, 0, len(paths))
+
+	for i, path := range paths {
+		b, err := loader.NewFileLoader().
+			WithProcessAnnotation(true).
+			WithBundleVerificationConfig(e.verification).
+			WithSkipBundleVerification(e.skipVerify).
+			AsBundle(path)
+		if err != nil {
+			// Errors already include the local path.
+			if refs[i] != path {
+				err = fmt.Errorf("bundle %s: %w", refs[i], err)
+			}
+
+			return nil, err
+		}
+
+		if e.verifying() && len(b.Signatures.Signatures) == 0 {
+			return nil, fmt.Errorf("bundle %s: bundle isn't signed", refs[i])
+		}
+
+		if err := checkManifest(b.Manifest); err != nil {
+			return nil, fmt.Errorf("bundle %s: %w", refs[i], err)
+		}
+
+		bundles = append(bundles, b)
+	}
+
+	return bundles, nil
+}
+
+// verifying reports whether bundles must be verified.
+func (e *Engine) verifying() bool {
+	return e.verification != nil && !e.skipVerify
+}
+
+// checkBundleRoots returns an error if any of modules is within roots,
+// since those packages are owned by a bundle.
+func checkBundleRoots(modules map[string]*ast.Module, roots []string) error {
+	for path, mod := range modules {
+		pkg, err := mod.Package.Path.Ptr()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		if bundle.RootPathsContain(roots, pkg) {
+			return fmt.Errorf("%s: package %s conflicts with bundle roots %v", path, mod.Package.Path, roots)
+		}
+	}
+
+	return nil
+}
+
+// isBundle reports whether path is a bundle, i.e. a .tar.gz file
+// or a directory with a .manifest file.
+func isBundle(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	if !info.IsDir() {
+		return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+	}
+
+	_, err = os.Stat(filepath.Join(path, bundle.ManifestExt))
+
+	return err == nil
 }
 
 func isRegoFile(_ string, info os.FileInfo, _ int) bool {
@@ -0,0 +1,94 @@
+package policy_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/reposaur/reposaur/internal/policy"
+)
+
+func benchmarkInput(i int) map[string]any {
+	return map[string]any{
+		"name":        fmt.Sprintf("repo-%d", i),
+		"description": "",
+		"topics":      []any{"misc"},
+		"branches": []any{
+			map[string]any{"name": "main", "protected": true},
+			map[string]any{"name": "dev", "protected": false},
+		},
+	}
+}
+
+// BenchmarkCheckCold loads a new engine for every input, so every
+// query is prepared on each check. Loading isn't timed.
+func BenchmarkCheckCold(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		engine := loadTestEngine(b)
+		b.StartTimer()
+
+		if _, err := engine.Check(context.Background(), "github.repository", benchmarkInput(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCheckUnprepared evaluates the rules of an engine without
+// preparing their queries, i.e. with a new query for each rule and
+// input, as checks did before queries were prepared.
+func BenchmarkCheckUnprepared(b *testing.B) {
+	engine := loadTestEngine(b)
+
+	rules, err := engine.Rules()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var queries []string
+	for _, r := range rules {
+		if r.Namespace != "github.repository" {
+			continue
+		}
+
+		queries = append(queries,
+			fmt.Sprintf("data.%s.%s_%s", r.Namespace, r.Kind, r.ID),
+			fmt.Sprintf("data.%s.skip[_][_] == %q", r.Namespace, r.ID),
+		)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, query := range queries {
+			_, err := rego.New(
+				rego.Query(query),
+				rego.Compiler(engine.Compiler()),
+				rego.Store(engine.Store()),
+				rego.Input(benchmarkInput(i)),
+			).Eval(context.Background())
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkCheckWarm reuses an engine across inputs, so queries are
+// only prepared once.
+func BenchmarkCheckWarm(b *testing.B) {
+	for _, concurrency := range []int{1, 4} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			engine := loadTestEngine(b, policy.WithConcurrency(concurrency))
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := engine.Check(context.Background(), "github.repository", benchmarkInput(i)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
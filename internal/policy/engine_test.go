@@ -0,0 +1,296 @@
+package policy_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+
+	"github.com/reposaur/reposaur/internal/policy"
+	"github.com/reposaur/reposaur/pkg/output"
+)
+
+func loadTestEngine(t testing.TB, opts ...policy.Option) *policy.Engine {
+	t.Helper()
+
+	engine, err := policy.Load(context.Background(), []string{"testdata/policy"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return engine
+}
+
+func resultsByRule(report output.Report) map[string][]*output.Result {
+	results := map[string][]*output.Result{}
+	for _, r := range report.Results {
+		results[r.Rule.ID] = append(results[r.Rule.ID], r)
+	}
+
+	return results
+}
+
+func TestCheckSetRuleResults(t *testing.T) {
+	engine := loadTestEngine(t)
+
+	report, err := engine.Check(context.Background(), "github.repository", map[string]any{
+		"description": "Reposaur",
+		"topics":      []any{"team-platform", "misc"},
+		"branches": []any{
+			map[string]any{"name": "main", "protected": true},
+			map[string]any{"name": "dev", "protected": false},
+			map[string]any{"name": "release", "protected": false},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := resultsByRule(report)
+
+	if r := results["missing_description"]; len(r) != 1 || !r[0].Passed {
+		t.Fatalf("expected missing_description to pass, got %v", r)
+	}
+
+	branches := results["unprotected_branches"]
+	if len(branches) != 2 {
+		t.Fatalf("expected 2 unprotected_branches results got %d", len(branches))
+	}
+
+	if branches[0].Message != "Branch dev is not protected" || branches[1].Message != "Branch release is not protected" {
+		t.Fatalf("unexpected messages: %q, %q", branches[0].Message, branches[1].Message)
+	}
+
+	topics := results["invalid_topics"]
+	if len(topics) != 1 {
+		t.Fatalf("expected 1 invalid_topics result got %d", len(topics))
+	}
+
+	if topics[0].Location != "misc" || topics[0].Metadata["prefix"] != "team-" || !topics[0].Failed() {
+		t.Fatalf("unexpected invalid_topics result: %+v", topics[0])
+	}
+}
+
+func TestCheckBooleanRuleAndSkip(t *testing.T) {
+	engine := loadTestEngine(t)
+
+	report, err := engine.Check(context.Background(), "github.repository", map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := resultsByRule(report)
+
+	if r := results["missing_description"]; len(r) != 1 || !r[0].Failed() || r[0].Text() != "Repository has a description" {
+		t.Fatalf("expected missing_description to fail with the rule title, got %+v", r[0])
+	}
+
+	if r := results["unprotected_branches"]; len(r) != 1 || !r[0].Passed {
+		t.Fatalf("expected an empty set rule to pass, got %+v", r[0])
+	}
+
+	report, err = engine.Check(context.Background(), "github.repository", map[string]any{"archived": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := resultsByRule(report)["missing_description"]; len(r) != 1 || !r[0].Skipped {
+		t.Fatalf("expected missing_description to be skipped, got %+v", r[0])
+	}
+}
+
+func init() {
+	// test.sleep blocks for the given number of milliseconds
+	// or until the evaluation is cancelled.
+	rego.RegisterBuiltin1(&rego.Function{
+		Name: "test.sleep",
+		Decl: types.NewFunction(types.Args(types.N), types.B),
+	}, func(bctx rego.BuiltinContext, op *ast.Term) (*ast.Term, error) {
+		var ms int
+		if err := ast.As(op.Value, &ms); err != nil {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(time.Duration(ms) * time.Millisecond):
+			return ast.BooleanTerm(true), nil
+		case <-bctx.Context.Done():
+			return nil, bctx.Context.Err()
+		}
+	})
+}
+
+func TestCheckTimeouts(t *testing.T) {
+	tests := []struct {
+		opts     []policy.Option
+		sleep    int
+		expected string
+	}{
+		{opts: []policy.Option{policy.WithRuleTimeout(50 * time.Millisecond)}, sleep: 5000, expected: "rule evaluation timed out after 50ms"},
+		{opts: []policy.Option{policy.WithInputTimeout(50 * time.Millisecond)}, sleep: 5000, expected: "input evaluation timed out after 50ms"},
+		{opts: []policy.Option{policy.WithRuleTimeout(5 * time.Second)}, sleep: 1},
+	}
+
+	for _, tt := range tests {
+		engine, err := policy.Load(context.Background(), []string{"testdata/timeout"}, tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := engine.Check(context.Background(), "github.repository", map[string]any{
+			"description": "Reposaur",
+			"sleep":       tt.sleep,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results := resultsByRule(report)
+
+		slow := results["slow"]
+		if len(slow) != 1 || slow[0].Error != tt.expected {
+			t.Fatalf("expected slow to have error '%s' got %+v", tt.expected, slow)
+		}
+
+		if tt.expected == "" && !slow[0].Failed() {
+			t.Fatalf("expected slow to fail got %+v", slow[0])
+		}
+
+		if !report.HasErrors() && tt.expected != "" {
+			t.Fatal("expected report to have errors")
+		}
+
+		// Timeouts never abort the evaluation of the other rules.
+		description := results["missing_description"]
+		if len(description) != 1 {
+			t.Fatalf("expected 1 missing_description result got %d", len(description))
+		}
+	}
+}
+
+func TestCheckErrorResults(t *testing.T) {
+	engine, err := policy.Load(context.Background(), []string{"testdata/errors"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := engine.Check(context.Background(), "github.repository", map[string]any{"zero": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := resultsByRule(report)
+
+	broken := results["broken"]
+	if len(broken) != 1 || !broken[0].Errored() || broken[0].Failed() {
+		t.Fatalf("expected broken to have an error result got %+v", broken)
+	}
+
+	if broken[0].Query != "data.github.repository.violation_broken" || !strings.Contains(broken[0].Error, "divide by zero") {
+		t.Fatalf("unexpected broken result: %+v", broken[0])
+	}
+
+	if description := results["missing_description"]; len(description) != 1 || !description[0].Failed() {
+		t.Fatalf("expected missing_description to fail got %+v", description)
+	}
+
+	// Cancellations by the caller still abort the check.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := engine.Check(ctx, "github.repository", map[string]any{}); err == nil {
+		t.Fatal("expected an error for a cancelled context")
+	}
+}
+
+// buildTestBundle returns a tarball of the bundle at testdata/bundle.
+func buildTestBundle(t testing.TB) []byte {
+	t.Helper()
+
+	b, err := loader.NewFileLoader().AsBundle("testdata/bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := bundle.Write(buf, *b); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestLoadBundle(t *testing.T) {
+	// Build a tarball from the bundle directory, so that both
+	// formats are loaded.
+	tarball := filepath.Join(t.TempDir(), "bundle.tar.gz")
+
+	if err := os.WriteFile(tarball, buildTestBundle(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"testdata/bundle", tarball} {
+		engine, err := policy.Load(context.Background(), []string{path})
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+
+		if m, ok := engine.Manifests()[path]; !ok || m.Revision != "v1.0.0" {
+			t.Fatalf("%s: expected manifest with revision v1.0.0 got %+v", path, engine.Manifests())
+		}
+
+		report, err := engine.Check(context.Background(), "github.organization", map[string]any{
+			"admins": []any{"a", "b", "c"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The rule reads the maximum from the bundle's data.
+		admins := resultsByRule(report)["too_many_admins"]
+		if len(admins) != 1 || !admins[0].Failed() {
+			t.Fatalf("%s: expected too_many_admins to fail got %+v", path, admins)
+		}
+
+		if admins[0].Rule.Title != "Organization has too many admins" {
+			t.Fatalf("%s: expected annotations to be loaded got %+v", path, admins[0].Rule)
+		}
+	}
+}
+
+func TestLoadBundleRootsConflict(t *testing.T) {
+	_, err := policy.Load(context.Background(), []string{"testdata/bundle", "testdata/conflict"})
+	if err == nil || !strings.Contains(err.Error(), "conflicts with bundle roots") {
+		t.Fatalf("expected roots conflict error got %v", err)
+	}
+}
+
+func TestRules(t *testing.T) {
+	engine, err := policy.Load(context.Background(), []string{"testdata/policy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := engine.Rules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := map[string]string{}
+	for _, r := range rules {
+		titles[r.UID()] = r.Title
+	}
+
+	if title := titles["github.repository/warn/invalid_topics"]; title != "Topics follow the naming convention" {
+		t.Fatalf("expected title from annotations got '%s'", title)
+	}
+}
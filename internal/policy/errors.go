@@ -1,6 +1,9 @@
 package policy
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type ErrPolicyLoad struct {
 	loaderError error
@@ -17,3 +20,31 @@ type ErrNoPolicies struct {
 func (e *ErrNoPolicies) Error() string {
 	return fmt.Sprintf("no policy .rego files found in %v", e.policyPaths)
 }
+
+// ErrEval is returned when a query can't be evaluated.
+type ErrEval struct {
+	Query string
+	Err   error
+}
+
+func (e *ErrEval) Error() string {
+	return fmt.Sprintf("eval %s: %v", e.Query, e.Err)
+}
+
+func (e *ErrEval) Unwrap() error {
+	return e.Err
+}
+
+// ErrInvalidRules is returned when rules can't be parsed.
+type ErrInvalidRules struct {
+	Errs []error
+}
+
+func (e *ErrInvalidRules) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("invalid rules:\n%s", strings.Join(msgs, "\n"))
+}
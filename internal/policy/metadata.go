@@ -0,0 +1,222 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/reposaur/reposaur/internal/build"
+)
+
+// MetadataKey is the key of the Reposaur metadata in bundle manifests.
+const MetadataKey = "reposaur"
+
+// Metadata describes the requirements of a bundle.
+type Metadata struct {
+	// MinVersion is the minimum version of Reposaur
+	// required to load the bundle, e.g. v0.10.0.
+	MinVersion string `json:"min_version,omitempty"`
+
+	// Providers are the providers whose builtins are
+	// used by the bundle, e.g. github.
+	Providers []string `json:"providers,omitempty"`
+
+	// Annotations are the annotations of rules by rule path, e.g.
+	// data.github.repository.warn_missing_description. Optimized
+	// bundles include them, since optimizations rewrite rules
+	// without their METADATA comments.
+	Annotations map[string]RuleAnnotations `json:"annotations,omitempty"`
+}
+
+// RuleAnnotations are the annotations of a rule used by Reposaur.
+type RuleAnnotations struct {
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Custom      map[string]interface{} `json:"custom,omitempty"`
+}
+
+// NewRuleAnnotations returns the annotations in a used by Reposaur.
+func NewRuleAnnotations(a *ast.Annotations) RuleAnnotations {
+	return RuleAnnotations{
+		Title:       a.Title,
+		Description: a.Description,
+		Custom:      a.Custom,
+	}
+}
+
+// Map returns a as a manifest metadata value.
+func (a RuleAnnotations) Map() map[string]interface{} {
+	m := map[string]interface{}{}
+
+	if a.Title != "" {
+		m["title"] = a.Title
+	}
+
+	if a.Description != "" {
+		m["description"] = a.Description
+	}
+
+	if len(a.Custom) > 0 {
+		m["custom"] = a.Custom
+	}
+
+	return m
+}
+
+// annotations returns a as the annotations of a rule.
+func (a RuleAnnotations) annotations() *ast.Annotations {
+	return &ast.Annotations{
+		Scope:       "rule",
+		Title:       a.Title,
+		Description: a.Description,
+		Custom:      a.Custom,
+	}
+}
+
+// ManifestMetadata returns the Reposaur metadata in m, if any.
+func ManifestMetadata(m bundle.Manifest) (*Metadata, error) {
+	raw, ok := m.Metadata[MetadataKey]
+	if !ok {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var md Metadata
+	if err := json.Unmarshal(b, &md); err != nil {
+		return nil, fmt.Errorf("invalid %s metadata: %w", MetadataKey, err)
+	}
+
+	return &md, nil
+}
+
+// Map returns md as a manifest metadata value.
+func (md Metadata) Map() map[string]interface{} {
+	m := map[string]interface{}{}
+
+	if md.MinVersion != "" {
+		m["min_version"] = md.MinVersion
+	}
+
+	if len(md.Providers) > 0 {
+		providers := make([]interface{}, 0, len(md.Providers))
+		for _, p := range md.Providers {
+			providers = append(providers, p)
+		}
+
+		m["providers"] = providers
+	}
+
+	if len(md.Annotations) > 0 {
+		annotations := make(map[string]interface{}, len(md.Annotations))
+		for path, a := range md.Annotations {
+			annotations[path] = a.Map()
+		}
+
+		m["annotations"] = annotations
+	}
+
+	return m
+}
+
+// Validate returns an error if md is malformed, e.g. if
+// MinVersion isn't in the form [v]MAJOR.MINOR.PATCH.
+func (md Metadata) Validate() error {
+	if md.MinVersion != "" {
+		if _, ok := parseVersion(md.MinVersion); !ok {
+			return fmt.Errorf("invalid minimum version '%s'", md.MinVersion)
+		}
+	}
+
+	return nil
+}
+
+// checkManifest returns an error if the requirements in the
+// metadata of m aren't met by this build of Reposaur.
+func checkManifest(m bundle.Manifest) error {
+	md, err := ManifestMetadata(m)
+	if err != nil || md == nil {
+		return err
+	}
+
+	if err := md.Validate(); err != nil {
+		return err
+	}
+
+	if md.MinVersion != "" {
+		min, _ := parseVersion(md.MinVersion)
+
+		// Development builds, i.e. DEV or untagged pseudo-versions
+		// like v0.0.0-20220101000000-abcdef, satisfy any version.
+		if current, ok := parseVersion(build.Version); ok && current != [3]int{} && compareVersions(current, min) < 0 {
+			return fmt.Errorf("requires reposaur %s or later, running %s", md.MinVersion, build.Version)
+		}
+	}
+
+	for _, p := range md.Providers {
+		if !providerRegistered(p) {
+			return fmt.Errorf("requires the %s provider", p)
+		}
+	}
+
+	return nil
+}
+
+// providerRegistered reports whether any builtin of the
+// provider named name is registered.
+func providerRegistered(name string) bool {
+	for b := range ast.BuiltinMap {
+		if strings.HasPrefix(b, name+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseVersion parses a version in the form [v]MAJOR.MINOR.PATCH, ignoring
+// pre-release and build suffixes.
+func parseVersion(v string) ([3]int, bool) {
+	var parsed [3]int
+
+	v = strings.TrimPrefix(v, "v")
+
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+
+		parsed[i] = n
+	}
+
+	return parsed, true
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+
+	return 0
+}
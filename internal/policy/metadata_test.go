@@ -0,0 +1,64 @@
+package policy_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/loader"
+
+	"github.com/reposaur/reposaur/internal/build"
+	"github.com/reposaur/reposaur/internal/policy"
+)
+
+func TestLoadBundleMetadata(t *testing.T) {
+	version := build.Version
+	t.Cleanup(func() { build.Version = version })
+
+	tests := []struct {
+		version  string
+		metadata policy.Metadata
+		expected string
+	}{
+		{metadata: policy.Metadata{}},
+		{version: "DEV", metadata: policy.Metadata{MinVersion: "v0.11.0"}},
+		{version: "v0.0.0-20220101000000-abcdef123456", metadata: policy.Metadata{MinVersion: "v0.11.0"}},
+		{metadata: policy.Metadata{MinVersion: "0.9.2"}},
+		{metadata: policy.Metadata{MinVersion: "v0.10.0"}},
+		{metadata: policy.Metadata{MinVersion: "v0.11.0"}, expected: "requires reposaur v0.11.0 or later"},
+		{metadata: policy.Metadata{MinVersion: "latest"}, expected: "invalid minimum version"},
+		{metadata: policy.Metadata{Providers: []string{"test"}}},
+		{metadata: policy.Metadata{Providers: []string{"test", "gitlab"}}, expected: "requires the gitlab provider"},
+	}
+
+	dir := t.TempDir()
+
+	for i, tt := range tests {
+		build.Version = "v0.10.0"
+		if tt.version != "" {
+			build.Version = tt.version
+		}
+
+		b, err := loader.NewFileLoader().AsBundle("testdata/bundle")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b.Manifest.Metadata = map[string]interface{}{
+			policy.MetadataKey: tt.metadata.Map(),
+		}
+
+		path := writeTestBundle(t, dir, fmt.Sprintf("%d.tar.gz", i), *b)
+
+		_, err = policy.Load(context.Background(), []string{path})
+
+		if tt.expected == "" && err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Fatalf("%d: expected error '%s' got %v", i, tt.expected, err)
+		}
+	}
+}
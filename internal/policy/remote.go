@@ -0,0 +1,228 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/reposaur/reposaur/internal/oci"
+)
+
+// digestFragment is the prefix of the URL fragment that pins the
+// digest of bundles fetched over HTTP, e.g. #sha256=<hex>.
+const digestFragment = "sha256="
+
+// isRemote reports whether path references a bundle that
+// must be fetched first.
+func isRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") ||
+		strings.HasPrefix(path, "https://") ||
+		strings.HasPrefix(path, oci.Scheme)
+}
+
+// fetchBundle downloads the bundle referenced by ref into the cache and
+// returns its local path. Bundles are cached by digest, so pinned
+// bundles are only downloaded once and verified every time they're
+// loaded.
+func (e *Engine) fetchBundle(ctx context.Context, ref string) (string, error) {
+	cache, err := e.bundleCache()
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(ref, oci.Scheme) {
+		return e.fetchOCIBundle(ctx, cache, ref)
+	}
+
+	return e.fetchHTTPBundle(ctx, cache, ref)
+}
+
+func (e *Engine) fetchHTTPBundle(ctx context.Context, cache *blobCache, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("fetch %s: %w", ref, err)
+	}
+
+	var digest string
+	if u.Fragment != "" {
+		if !strings.HasPrefix(u.Fragment, digestFragment) {
+			return "", fmt.Errorf("fetch %s: unsupported digest '%s', expected sha256=<hex>", ref, u.Fragment)
+		}
+
+		digest = "sha256:" + strings.TrimPrefix(u.Fragment, digestFragment)
+		u.Fragment = ""
+	}
+
+	// Bundles sent in plain text could be tampered with, unless
+	// pinned. Local servers are trusted, like local registries.
+	if digest == "" && u.Scheme == "http" && !oci.IsLocalhost(u.Host) {
+		return "", fmt.Errorf("fetch %s: bundles fetched over plain HTTP must be pinned with #%s<hex>", ref, digestFragment)
+	}
+
+	if digest != "" {
+		if path, ok := cache.get(digest, bundleExt); ok {
+			return path, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("fetch %s: %w", ref, err)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch %s: unexpected status %d", ref, resp.StatusCode)
+	}
+
+	b, err := oci.ReadLimited(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("fetch %s: %w", ref, err)
+	}
+
+	if digest != "" {
+		if err := oci.Verify(b, digest); err != nil {
+			return "", fmt.Errorf("fetch %s: %w", ref, err)
+		}
+	}
+
+	return cache.set(b, bundleExt)
+}
+
+func (e *Engine) fetchOCIBundle(ctx context.Context, cache *blobCache, s string) (string, error) {
+	ref, err := oci.ParseReference(s)
+	if err != nil {
+		return "", err
+	}
+
+	var manifest *oci.Manifest
+
+	// Pinned manifests are read from the cache if present.
+	if ref.Digest != "" {
+		if path, ok := cache.get(ref.Digest, manifestExt); ok {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("fetch %s: %w", ref, err)
+			}
+
+			if manifest, err = oci.DecodeManifest(b); err != nil {
+				return "", fmt.Errorf("fetch %s: %w", ref, err)
+			}
+		}
+	}
+
+	if manifest == nil {
+		var b []byte
+		if manifest, b, err = e.ociClient.FetchManifest(ctx, ref); err != nil {
+			return "", err
+		}
+
+		if _, err := cache.set(b, manifestExt); err != nil {
+			return "", err
+		}
+	}
+
+	layer, err := manifest.BundleLayer()
+	if err != nil {
+		return "", fmt.Errorf("fetch %s: %w", ref, err)
+	}
+
+	if path, ok := cache.get(layer.Digest, bundleExt); ok {
+		return path, nil
+	}
+
+	b, err := e.ociClient.FetchBlob(ctx, ref, layer.Digest)
+	if err != nil {
+		return "", err
+	}
+
+	return cache.set(b, bundleExt)
+}
+
+func (e *Engine) bundleCache() (*blobCache, error) {
+	dir := e.bundleCacheDir
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			userDir = os.TempDir()
+		}
+
+		dir = filepath.Join(userDir, "reposaur", "bundles")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("bundle cache: %w", err)
+	}
+
+	return &blobCache{dir: dir}, nil
+}
+
+const (
+	bundleExt   = ".tar.gz"
+	manifestExt = ".json"
+)
+
+// blobCache stores blobs in a directory, named by their digest.
+type blobCache struct {
+	dir string
+}
+
+// get returns the path of the blob with digest, if it's cached and
+// its contents match the digest.
+func (c *blobCache) get(digest, ext string) (string, bool) {
+	if oci.ValidateDigest(digest) != nil {
+		return "", false
+	}
+
+	path := c.path(digest, ext)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	if oci.Verify(b, digest) != nil {
+		return "", false
+	}
+
+	return path, true
+}
+
+// set stores b in the cache and returns its path.
+func (c *blobCache) set(b []byte, ext string) (string, error) {
+	path := c.path(oci.Digest(b), ext)
+
+	f, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("bundle cache: %w", err)
+	}
+
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("bundle cache: %w", err)
+	}
+
+	return path, nil
+}
+
+func (c *blobCache) path(digest, ext string) string {
+	return filepath.Join(c.dir, strings.Replace(digest, ":", "-", 1)+ext)
+}
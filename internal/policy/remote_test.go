@@ -0,0 +1,118 @@
+package policy_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/reposaur/reposaur/internal/oci"
+	"github.com/reposaur/reposaur/internal/oci/ocitest"
+	"github.com/reposaur/reposaur/internal/policy"
+)
+
+func TestLoadHTTPBundle(t *testing.T) {
+	var (
+		calls  int32
+		bundle = buildTestBundle(t)
+		digest = strings.TrimPrefix(oci.Digest(bundle), "sha256:")
+		opts   = []policy.Option{policy.WithBundleCacheDir(t.TempDir())}
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = w.Write(bundle)
+	}))
+	defer srv.Close()
+
+	pinned := srv.URL + "/bundle.tar.gz#sha256=" + digest
+
+	for i := 0; i < 2; i++ {
+		engine, err := policy.Load(context.Background(), []string{pinned}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m := engine.Manifests()[pinned]; m.Revision != "v1.0.0" {
+			t.Fatalf("expected manifest with revision v1.0.0 got %+v", engine.Manifests())
+		}
+	}
+
+	// Pinned bundles are only downloaded once.
+	if calls != 1 {
+		t.Fatalf("expected 1 request got %d", calls)
+	}
+
+	wrong := srv.URL + "/bundle.tar.gz#sha256=" + strings.Repeat("0", len(digest))
+
+	if _, err := policy.Load(context.Background(), []string{wrong}, opts...); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatalf("expected digest mismatch error got %v", err)
+	}
+
+	// Unpinned bundles are always downloaded.
+	if _, err := policy.Load(context.Background(), []string{srv.URL + "/bundle.tar.gz"}, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected 3 requests got %d", calls)
+	}
+}
+
+func TestLoadHTTPBundleUnpinned(t *testing.T) {
+	opts := []policy.Option{policy.WithBundleCacheDir(t.TempDir())}
+
+	// Refused before sending any request.
+	_, err := policy.Load(context.Background(), []string{"http://example.com/bundle.tar.gz"}, opts...)
+	if err == nil || !strings.Contains(err.Error(), "must be pinned") {
+		t.Fatalf("expected unpinned bundle error got %v", err)
+	}
+}
+
+func TestLoadOCIBundle(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		registry = ocitest.NewRegistry(t)
+		opts     = []policy.Option{policy.WithBundleCacheDir(t.TempDir())}
+	)
+
+	ref, err := oci.ParseReference("oci://" + registry.Host() + "/reposaur/policies:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ref.Digest, err = oci.NewClient("", "").Push(ctx, ref, buildTestBundle(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := policy.Load(ctx, []string{ref.String()}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := engine.Check(ctx, "github.organization", map[string]any{
+		"admins": []any{"a", "b", "c"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if admins := resultsByRule(report)["too_many_admins"]; len(admins) != 1 || !admins[0].Failed() {
+		t.Fatalf("expected too_many_admins to fail got %+v", admins)
+	}
+
+	// Pinned bundles are loaded from the cache once downloaded.
+	registry.Close()
+
+	if _, err := policy.Load(ctx, []string{ref.String()}, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	ref.Digest = oci.Digest([]byte("another manifest"))
+
+	if _, err := policy.Load(ctx, []string{ref.String()}, opts...); err == nil {
+		t.Fatal("expected an error loading an unknown manifest")
+	}
+}
@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/reposaur/reposaur/pkg/output"
+)
+
+// newRuleResults converts the value produced by a rule into results:
+//
+//   - false or an empty set/object produce a single passed result
+//   - true produces a single failed result, using the rule title as message
+//   - a set (e.g. violation_foo[msg]) produces a failed result per member
+//   - an object with a "message" or "msg" field produces a single failed result
+//   - any other object (e.g. violation_foo[key] = value) produces a failed
+//     result per value
+//
+// See newFailedResult for how values are converted into results.
+func newRuleResults(rule *output.Rule, query string, value interface{}) []*output.Result {
+	passed := []*output.Result{{Rule: rule, Query: query, Passed: true}}
+
+	switch v := value.(type) {
+	case bool:
+		if !v {
+			return passed
+		}
+
+		return []*output.Result{{Rule: rule, Query: query}}
+
+	case []interface{}:
+		if len(v) == 0 {
+			return passed
+		}
+
+		results := make([]*output.Result, 0, len(v))
+		for _, item := range v {
+			results = append(results, newFailedResult(rule, query, item))
+		}
+
+		return results
+
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return passed
+		}
+
+		if isMessageObject(v) {
+			return []*output.Result{newFailedResult(rule, query, v)}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		results := make([]*output.Result, 0, len(v))
+		for _, k := range keys {
+			result := newFailedResult(rule, query, v[k])
+			if result.Message == "" {
+				result.Message = k
+			}
+
+			results = append(results, result)
+		}
+
+		return results
+	}
+
+	return []*output.Result{newFailedResult(rule, query, value)}
+}
+
+// newFailedResult converts a single value produced by a rule into a
+// failed result. Strings are used as the message. Objects can have
+// "message" (or "msg") and "location" fields, any other fields are kept
+// as metadata. An explicit "metadata" object is merged into metadata.
+func newFailedResult(rule *output.Rule, query string, value interface{}) *output.Result {
+	result := &output.Result{
+		Rule:  rule,
+		Query: query,
+	}
+
+	switch v := value.(type) {
+	case string:
+		result.Message = v
+
+	case map[string]interface{}:
+		metadata := map[string]interface{}{}
+
+		for k, fv := range v {
+			switch k {
+			case "message", "msg":
+				result.Message = toString(fv)
+
+			case "location":
+				result.Location = toString(fv)
+
+			case "metadata":
+				if m, ok := fv.(map[string]interface{}); ok {
+					for mk, mv := range m {
+						metadata[mk] = mv
+					}
+				} else {
+					metadata[k] = fv
+				}
+
+			default:
+				metadata[k] = fv
+			}
+		}
+
+		if len(metadata) > 0 {
+			result.Metadata = metadata
+		}
+
+	case bool, nil:
+		// Nothing describes the failure, Result.Text falls back
+		// to the rule title
+
+	default:
+		result.Message = toString(v)
+	}
+
+	return result
+}
+
+func isMessageObject(v map[string]interface{}) bool {
+	_, hasMessage := v["message"]
+	_, hasMsg := v["msg"]
+
+	return hasMessage || hasMsg
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(b)
+}
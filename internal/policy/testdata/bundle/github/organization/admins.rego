@@ -0,0 +1,7 @@
+package github.organization
+
+# METADATA
+# title: Organization has too many admins
+violation_too_many_admins {
+	count(input.admins) > data.reposaur.config.max_admins
+}
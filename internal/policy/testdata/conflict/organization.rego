@@ -0,0 +1,5 @@
+package github.organization
+
+violation_no_description {
+	not input.description
+}
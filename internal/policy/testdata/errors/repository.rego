@@ -0,0 +1,9 @@
+package github.repository
+
+violation_broken {
+	1 / input.zero
+}
+
+violation_missing_description {
+	not input.description
+}
@@ -0,0 +1,30 @@
+package github.repository
+
+import future.keywords.in
+
+# METADATA
+# title: Repository has a description
+violation_missing_description {
+	not input.description
+}
+
+# METADATA
+# title: Branches are protected
+violation_unprotected_branches[msg] {
+	some branch in input.branches
+	not branch.protected
+	msg := sprintf("Branch %s is not protected", [branch.name])
+}
+
+# METADATA
+# title: Topics follow the naming convention
+warn_invalid_topics[{"message": msg, "location": topic, "prefix": "team-"}] {
+	some topic in input.topics
+	not startswith(topic, "team-")
+	msg := sprintf("Topic %s doesn't start with team-", [topic])
+}
+
+skip[rule] {
+	input.archived
+	rule := ["missing_description"]
+}
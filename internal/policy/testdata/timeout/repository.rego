@@ -0,0 +1,9 @@
+package github.repository
+
+violation_slow {
+	test.sleep(input.sleep)
+}
+
+violation_missing_description {
+	not input.description
+}
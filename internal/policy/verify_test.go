@@ -0,0 +1,116 @@
+package policy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/keys"
+	"github.com/open-policy-agent/opa/loader"
+
+	"github.com/reposaur/reposaur/internal/policy"
+)
+
+// newTestKeys returns a PEM encoded RSA private key and its public key.
+func newTestKeys(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})
+
+	return string(privPEM), string(pubPEM)
+}
+
+func writeTestBundle(t *testing.T, dir, name string, b bundle.Bundle) string {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	if err := bundle.Write(buf, b); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadSignedBundle(t *testing.T) {
+	var (
+		dir         = t.TempDir()
+		priv, pub   = newTestKeys(t)
+		_, otherPub = newTestKeys(t)
+
+		// Key sets don't set a key ID, signatures identify the key.
+		verification = func(pub, keyID string) policy.Option {
+			return policy.WithBundleVerification(bundle.NewVerificationConfig(map[string]*keys.Config{
+				"default": {Key: pub, Algorithm: "RS256"},
+			}, keyID, "", nil))
+		}
+	)
+
+	b, err := loader.NewFileLoader().AsBundle("testdata/bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsigned := writeTestBundle(t, dir, "unsigned.tar.gz", *b)
+
+	if err := b.GenerateSignature(bundle.NewSigningConfig(priv, "RS256", ""), "default", false); err != nil {
+		t.Fatal(err)
+	}
+
+	signed := writeTestBundle(t, dir, "signed.tar.gz", *b)
+
+	b.Modules[0].Raw = bytes.Replace(b.Modules[0].Raw, []byte("> data"), []byte(">= data"), 1)
+	tampered := writeTestBundle(t, dir, "tampered.tar.gz", *b)
+
+	tests := []struct {
+		paths    []string
+		opts     []policy.Option
+		expected string
+	}{
+		{paths: []string{signed}, opts: []policy.Option{verification(pub, "default")}},
+		{paths: []string{signed}, opts: []policy.Option{verification(otherPub, "default")}, expected: "verification error"},
+		{paths: []string{unsigned}, opts: []policy.Option{verification(pub, "default")}, expected: "missing .signatures.json"},
+		{paths: []string{unsigned}, opts: []policy.Option{verification(pub, "")}, expected: "isn't signed"},
+		{paths: []string{signed}, opts: []policy.Option{verification(pub, "")}},
+		{paths: []string{tampered}, opts: []policy.Option{verification(pub, "default")}, expected: "digest mismatch"},
+		{paths: []string{tampered}, opts: []policy.Option{verification(pub, "default"), policy.WithSkipBundleVerification(true)}},
+		{paths: []string{signed, "testdata/policy"}, opts: []policy.Option{verification(pub, "default")}, expected: "can't be verified"},
+		{paths: []string{signed}, expected: "verification key not provided"},
+		{paths: []string{unsigned}},
+	}
+
+	for i, tt := range tests {
+		_, err := policy.Load(context.Background(), tt.paths, tt.opts...)
+
+		if tt.expected == "" && err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Fatalf("%d: expected error '%s' got %v", i, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type Format string
+
+const (
+	SarifFormat     Format = "sarif"
+	JSONFormat      Format = "json"
+	JSONLinesFormat Format = "jsonl"
+	TableFormat     Format = "table"
+	JUnitFormat     Format = "junit"
+)
+
+// Formats lists every supported output format.
+var Formats = []Format{
+	SarifFormat,
+	JSONFormat,
+	JSONLinesFormat,
+	TableFormat,
+	JUnitFormat,
+}
+
+// Encoder writes reports to an output stream.
+type Encoder interface {
+	// Encode writes a report to the output stream. Encoders
+	// may buffer reports until Close is called.
+	Encode(Report) error
+
+	// Close writes any buffered output. It doesn't close
+	// the underlying output stream.
+	Close() error
+}
+
+type EncoderOption func(*encoderOptions)
+
+type encoderOptions struct {
+	colors   bool
+	combined bool
+}
+
+// WithColors enables or disables colored output in
+// formats that support it.
+func WithColors(enabled bool) EncoderOption {
+	return func(o *encoderOptions) {
+		o.colors = enabled
+	}
+}
+
+// WithCombined enables or disables combining every report into a single
+// document. Only affects formats that write a document per report.
+func WithCombined(enabled bool) EncoderOption {
+	return func(o *encoderOptions) {
+		o.combined = enabled
+	}
+}
+
+// NewEncoder returns an Encoder that writes reports to w in format.
+func NewEncoder(w io.Writer, format Format, opts ...EncoderOption) (Encoder, error) {
+	options := &encoderOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	switch format {
+	case SarifFormat:
+		return &sarifEncoder{enc: newIndentedEncoder(w), combined: options.combined}, nil
+
+	case JSONFormat:
+		return &jsonEncoder{enc: newIndentedEncoder(w), combined: options.combined}, nil
+
+	case JSONLinesFormat:
+		return &jsonLinesEncoder{enc: json.NewEncoder(w)}, nil
+
+	case TableFormat:
+		return &tableEncoder{w: w, colors: options.colors}, nil
+
+	case JUnitFormat:
+		return &junitEncoder{w: w}, nil
+	}
+
+	return nil, fmt.Errorf("unknown output format '%s'", format)
+}
+
+func newIndentedEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc
+}
+
+// sarifEncoder writes each report as a SARIF document. If combined,
+// reports are buffered and written as a single SARIF document.
+type sarifEncoder struct {
+	enc      *json.Encoder
+	combined bool
+	reports  []Report
+}
+
+func (e *sarifEncoder) Encode(report Report) error {
+	if e.combined {
+		e.reports = append(e.reports, report)
+		return nil
+	}
+
+	sarif, err := NewSarifReport(report)
+	if err != nil {
+		return err
+	}
+
+	return e.enc.Encode(sarif)
+}
+
+func (e *sarifEncoder) Close() error {
+	if !e.combined {
+		return nil
+	}
+
+	sarif, err := NewCombinedSarifReport(e.reports)
+	if err != nil {
+		return err
+	}
+
+	return e.enc.Encode(sarif)
+}
+
+// jsonEncoder writes each report as a JSON document. If combined,
+// reports are buffered and written as a single JSON array.
+type jsonEncoder struct {
+	enc      *json.Encoder
+	combined bool
+	reports  []Report
+}
+
+func (e *jsonEncoder) Encode(report Report) error {
+	if e.combined {
+		e.reports = append(e.reports, report)
+		return nil
+	}
+
+	return e.enc.Encode(report)
+}
+
+func (e *jsonEncoder) Close() error {
+	if !e.combined {
+		return nil
+	}
+
+	if e.reports == nil {
+		e.reports = []Report{}
+	}
+
+	return e.enc.Encode(e.reports)
+}
+
+// jsonLinesEncoder writes each result as a compact JSON record
+// in its own line. See ResultRecord.
+type jsonLinesEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *jsonLinesEncoder) Encode(report Report) error {
+	for _, result := range report.Results {
+		if err := e.enc.Encode(NewResultRecord(report, result)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *jsonLinesEncoder) Close() error {
+	return nil
+}
+
+// ResultRecord is a flattened representation of a result, including
+// its rule and the properties of the report it belongs to.
+type ResultRecord struct {
+	RuleID     string                 `json:"ruleId"`
+	Namespace  string                 `json:"namespace"`
+	Kind       string                 `json:"kind"`
+	Severity   string                 `json:"severity"`
+	Title      string                 `json:"title"`
+	Message    string                 `json:"message"`
+	Location   string                 `json:"location,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Skipped    bool                   `json:"skipped"`
+	Passed     bool                   `json:"passed"`
+	Error      string                 `json:"error,omitempty"`
+	Query      string                 `json:"query,omitempty"`
+	Properties ReportProperties       `json:"properties,omitempty"`
+}
+
+func NewResultRecord(report Report, result *Result) ResultRecord {
+	record := ResultRecord{
+		RuleID:     result.Rule.UID(),
+		Namespace:  result.Rule.Namespace,
+		Kind:       result.Rule.Kind,
+		Severity:   result.Rule.Severity,
+		Title:      result.Rule.Title,
+		Message:    result.Text(),
+		Location:   result.Location,
+		Metadata:   result.Metadata,
+		Skipped:    result.Skipped,
+		Passed:     result.Passed,
+		Error:      result.Error,
+		Properties: report.Properties,
+	}
+
+	// The query is only useful to debug errors.
+	if result.Errored() {
+		record.Query = result.Query
+	}
+
+	return record
+}
@@ -0,0 +1,221 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestReport() Report {
+	rule := &Rule{
+		ID:        "unprotected_branches",
+		Title:     "Branches are protected",
+		Kind:      "violation",
+		Severity:  ErrorSeverity,
+		Namespace: "github.repository",
+	}
+
+	report := Report{
+		Rules:      map[string]*Rule{},
+		Properties: ReportProperties{"owner": "reposaur", "repo": "reposaur"},
+	}
+
+	report.AddRule(rule)
+	report.AddResult(&Result{Rule: rule, Message: "Branch dev is not protected"})
+	report.AddResult(&Result{Rule: rule, Message: "Branch release is not protected"})
+
+	return report
+}
+
+func TestJSONLinesEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	enc, err := NewEncoder(buf, JSONLinesFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Encode(newTestReport()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []ResultRecord
+
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var record ResultRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+
+		records = append(records, record)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records got %d", len(records))
+	}
+
+	if records[0].RuleID != "github.repository/violation/unprotected_branches" || records[0].Message != "Branch dev is not protected" {
+		t.Fatalf("unexpected record: %+v", records[0])
+	}
+
+	if records[1].Properties["repo"] != "reposaur" {
+		t.Fatalf("expected report properties in record, got %+v", records[1].Properties)
+	}
+}
+
+func TestTableEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	enc, err := NewEncoder(buf, TableFormat, WithColors(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Encode(newTestReport()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	for _, expected := range []string{
+		"github.repository\n",
+		"ERROR  violation/unprotected_branches  owner=reposaur repo=reposaur  Branch dev is not protected",
+		"2 failed, 0 passed, 0 skipped",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out)
+		}
+	}
+
+	if strings.Contains(out, "\x1b[") {
+		t.Error("expected output to not contain colors")
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := NewEncoder(&bytes.Buffer{}, "xml"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestCombinedSarifEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	enc, err := NewEncoder(buf, SarifFormat, WithCombined(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := enc.Encode(newTestReport()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Rules []any `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Runs) != 1 {
+		t.Fatalf("expected a single run got %d", len(doc.Runs))
+	}
+
+	if len(doc.Runs[0].Tool.Driver.Rules) != 1 || len(doc.Runs[0].Results) != 4 {
+		t.Fatalf("expected 1 rule and 4 results got %d and %d", len(doc.Runs[0].Tool.Driver.Rules), len(doc.Runs[0].Results))
+	}
+
+	if doc.Runs[0].Results[0].Properties["repo"] != "reposaur" {
+		t.Fatalf("expected report properties in results, got %v", doc.Runs[0].Results[0].Properties)
+	}
+}
+
+func TestSarifErrorNotifications(t *testing.T) {
+	report := newTestReport()
+	rule := report.Rules["github.repository/violation/unprotected_branches"]
+	report.AddResult(&Result{Rule: rule, Query: "data.github.repository.violation_unprotected_branches", Error: "rule evaluation timed out after 1s"})
+
+	if !report.HasErrors() {
+		t.Fatal("expected report to have errors")
+	}
+
+	sr, err := NewSarifReport(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := sr.Runs[0]
+
+	if len(run.Results) != 2 {
+		t.Fatalf("expected errors not to be results, got %d results", len(run.Results))
+	}
+
+	if len(run.Invocations) != 1 || *run.Invocations[0].ExecutionSuccessful {
+		t.Fatalf("expected an unsuccessful invocation got %+v", run.Invocations)
+	}
+
+	notifications := run.Invocations[0].ToolExecutionNotifications
+	if len(notifications) != 1 {
+		t.Fatalf("expected 1 notification got %d", len(notifications))
+	}
+
+	if n := notifications[0]; *n.Message.Text != "rule evaluation timed out after 1s" || *n.AssociatedRule.Id != rule.UID() || n.Properties["query"] != "data.github.repository.violation_unprotected_branches" {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+}
+
+func TestSarifInputErrorNotifications(t *testing.T) {
+	report := NewErrorReport(errors.New("could not derive a valid namespace from data"))
+
+	if !report.HasErrors() {
+		t.Fatal("expected report to have errors")
+	}
+
+	sr, err := NewCombinedSarifReport([]Report{newTestReport(), report})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := sr.Runs[0]
+
+	if len(run.Invocations) != 1 || *run.Invocations[0].ExecutionSuccessful {
+		t.Fatalf("expected an unsuccessful invocation got %+v", run.Invocations)
+	}
+
+	notifications := run.Invocations[0].ToolExecutionNotifications
+	if len(notifications) != 1 {
+		t.Fatalf("expected 1 notification got %d", len(notifications))
+	}
+
+	if n := notifications[0]; *n.Message.Text != "could not derive a valid namespace from data" || n.AssociatedRule != nil {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+}
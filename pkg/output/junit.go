@@ -0,0 +1,245 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/tester"
+)
+
+type JUnitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr,omitempty"`
+	Suites   []*JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr,omitempty"`
+	TestCases []*JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Failure   *JUnitMessage `xml:"failure,omitempty"`
+	Error     *JUnitMessage `xml:"error,omitempty"`
+	Skipped   *JUnitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type JUnitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// NewJUnitReport converts reports into JUnit test suites. Each namespace
+// becomes a test suite and each result becomes a test case, named after
+// its rule and the report properties. Inputs that couldn't be evaluated
+// become errored test cases in the input test suite.
+func NewJUnitReport(reports []Report) *JUnitTestSuites {
+	suites := &JUnitTestSuites{Name: "Reposaur"}
+	byNs := map[string]*JUnitTestSuite{}
+
+	suiteFor := func(name string) *JUnitTestSuite {
+		suite, ok := byNs[name]
+		if !ok {
+			suite = &JUnitTestSuite{Name: name}
+			byNs[name] = suite
+			suites.Suites = append(suites.Suites, suite)
+		}
+
+		return suite
+	}
+
+	for _, report := range reports {
+		props := formatProperties(report.Properties)
+
+		if report.Error != "" {
+			name := "input"
+			if props != "" {
+				name += " (" + props + ")"
+			}
+
+			suiteFor("input").addTestCase(&JUnitTestCase{
+				Name:  name,
+				Error: &JUnitMessage{Message: report.Error},
+			})
+		}
+
+		for _, result := range report.Results {
+			suite := suiteFor(result.Rule.Namespace)
+
+			name := result.Rule.Kind + "/" + result.Rule.ID
+			if props != "" {
+				name += " (" + props + ")"
+			}
+
+			testCase := &JUnitTestCase{
+				Name:      name,
+				ClassName: result.Rule.Namespace,
+			}
+
+			switch {
+			case result.Skipped:
+				testCase.Skipped = &JUnitMessage{}
+
+			case result.Errored():
+				testCase.Error = &JUnitMessage{Message: result.Error}
+
+			case !result.Passed:
+				testCase.Failure = &JUnitMessage{
+					Message: result.Text(),
+					Type:    result.Rule.Severity,
+					Text:    junitFailureText(result),
+				}
+			}
+
+			suite.addTestCase(testCase)
+		}
+	}
+
+	sort.Slice(suites.Suites, func(i, j int) bool {
+		return suites.Suites[i].Name < suites.Suites[j].Name
+	})
+
+	suites.sum()
+
+	return suites
+}
+
+// NewJUnitTestReport converts policy test results into JUnit test suites.
+// Each package becomes a test suite and each test becomes a test case.
+func NewJUnitTestReport(results []*tester.Result) *JUnitTestSuites {
+	suites := &JUnitTestSuites{Name: "Reposaur"}
+	byPkg := map[string]*JUnitTestSuite{}
+
+	for _, r := range results {
+		pkg := strings.TrimPrefix(r.Package, "data.")
+
+		suite, ok := byPkg[pkg]
+		if !ok {
+			suite = &JUnitTestSuite{Name: pkg}
+			byPkg[pkg] = suite
+			suites.Suites = append(suites.Suites, suite)
+		}
+
+		testCase := &JUnitTestCase{
+			Name:      r.Name,
+			ClassName: pkg,
+			Time:      fmt.Sprintf("%.3f", r.Duration.Seconds()),
+			SystemOut: string(r.Output),
+		}
+
+		switch {
+		case r.Error != nil:
+			testCase.Error = &JUnitMessage{Message: r.Error.Error()}
+
+		case r.Skip:
+			testCase.Skipped = &JUnitMessage{}
+
+		case r.Fail:
+			testCase.Failure = &JUnitMessage{Message: r.String()}
+			if r.FailedAt != nil {
+				testCase.Failure.Text = r.FailedAt.String()
+			}
+		}
+
+		suite.addTestCase(testCase)
+	}
+
+	suites.sum()
+
+	return suites
+}
+
+// Encode writes the test suites as an XML document to w.
+func (s *JUnitTestSuites) Encode(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (s *JUnitTestSuite) addTestCase(tc *JUnitTestCase) {
+	s.Tests++
+
+	switch {
+	case tc.Failure != nil:
+		s.Failures++
+	case tc.Error != nil:
+		s.Errors++
+	case tc.Skipped != nil:
+		s.Skipped++
+	}
+
+	s.TestCases = append(s.TestCases, tc)
+}
+
+func (s *JUnitTestSuites) sum() {
+	for _, suite := range s.Suites {
+		s.Tests += suite.Tests
+		s.Failures += suite.Failures
+		s.Errors += suite.Errors
+		s.Skipped += suite.Skipped
+	}
+}
+
+func junitFailureText(result *Result) string {
+	var lines []string
+
+	if result.Location != "" {
+		lines = append(lines, "location: "+result.Location)
+	}
+
+	keys := make([]string, 0, len(result.Metadata))
+	for k := range result.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("%s: %v", k, result.Metadata[k]))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// junitEncoder buffers every report and, when closed,
+// writes them as a single JUnit XML document.
+type junitEncoder struct {
+	w       io.Writer
+	reports []Report
+}
+
+func (e *junitEncoder) Encode(report Report) error {
+	e.reports = append(e.reports, report)
+	return nil
+}
+
+func (e *junitEncoder) Close() error {
+	return NewJUnitReport(e.reports).Encode(e.w)
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/open-policy-agent/opa/tester"
+)
+
+func TestNewJUnitReport(t *testing.T) {
+	report := newTestReport()
+
+	skippedRule := &Rule{ID: "archived", Kind: "warn", Severity: WarningSeverity, Namespace: "github.repository"}
+	report.AddRule(skippedRule)
+	report.AddResult(&Result{Rule: skippedRule, Skipped: true})
+
+	suites := NewJUnitReport([]Report{report})
+
+	if len(suites.Suites) != 1 || suites.Suites[0].Name != "github.repository" {
+		t.Fatalf("expected a single github.repository suite got %+v", suites.Suites)
+	}
+
+	if suites.Tests != 3 || suites.Failures != 2 || suites.Skipped != 1 {
+		t.Fatalf("unexpected totals: tests=%d failures=%d skipped=%d", suites.Tests, suites.Failures, suites.Skipped)
+	}
+
+	tc := suites.Suites[0].TestCases[0]
+	if tc.Name != "violation/unprotected_branches (owner=reposaur repo=reposaur)" || tc.Failure.Message != "Branch dev is not protected" {
+		t.Fatalf("unexpected test case: %+v", tc)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := suites.Encode(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded JUnitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Tests != 3 {
+		t.Fatalf("expected decoded report to have 3 tests got %d", decoded.Tests)
+	}
+}
+
+func TestNewJUnitReportInputErrors(t *testing.T) {
+	report := NewErrorReport(errors.New("could not derive a valid namespace from data"))
+
+	suites := NewJUnitReport([]Report{newTestReport(), report})
+
+	if len(suites.Suites) != 2 || suites.Suites[1].Name != "input" {
+		t.Fatalf("expected an input suite got %+v", suites.Suites)
+	}
+
+	if suites.Errors != 1 {
+		t.Fatalf("expected 1 error got %d", suites.Errors)
+	}
+
+	if tc := suites.Suites[1].TestCases[0]; tc.Error == nil || tc.Error.Message != "could not derive a valid namespace from data" {
+		t.Fatalf("unexpected test case: %+v", tc)
+	}
+}
+
+func TestNewJUnitTestReport(t *testing.T) {
+	suites := NewJUnitTestReport([]*tester.Result{
+		{Package: "data.github.repository", Name: "test_pass"},
+		{Package: "data.github.repository", Name: "test_fail", Fail: true},
+		{Package: "data.github.repository", Name: "test_error", Error: errors.New("boom")},
+		{Package: "data.github.organization", Name: "test_skip", Skip: true},
+	})
+
+	if len(suites.Suites) != 2 {
+		t.Fatalf("expected 2 suites got %d", len(suites.Suites))
+	}
+
+	if suites.Tests != 4 || suites.Failures != 1 || suites.Errors != 1 || suites.Skipped != 1 {
+		t.Fatalf("unexpected totals: %+v", suites)
+	}
+
+	if suites.Suites[0].TestCases[2].Error.Message != "boom" {
+		t.Fatalf("expected error message to be kept")
+	}
+}
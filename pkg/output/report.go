@@ -1,7 +1,10 @@
 package output
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/open-policy-agent/opa/ast"
@@ -21,17 +24,45 @@ var SeverityRuleMap = map[string][]string{
 	NoteSeverity:    {"note", "info"},
 }
 
+// SeverityLevelMap maps each severity to its level. Higher levels
+// are more severe.
+var SeverityLevelMap = map[string]int{
+	ErrorSeverity:   3,
+	WarningSeverity: 2,
+	NoteSeverity:    1,
+}
+
 var SecuritySeverityMap = map[string]string{
 	ErrorSeverity:   "7",
 	WarningSeverity: "4",
 	NoteSeverity:    "1",
 }
 
+// Report is the result of checking rules against an input.
 type Report struct {
-	Rules      map[string]*Rule   `json:"rules"`
-	Results    map[string]*Result `json:"results"`
-	RuleCount  int                `json:"ruleCount"`
-	Properties ReportProperties   `json:"properties"`
+	Rules map[string]*Rule `json:"rules"`
+
+	// Results are the results of every rule, in the order they were
+	// added. Rules can have more than one result, so results aren't
+	// keyed by rule UID, see ResultsByRule. In JSON, results keeps
+	// the ResultsByRule shape and every result is in allResults.
+	Results []*Result `json:"-"`
+
+	RuleCount  int              `json:"ruleCount"`
+	Properties ReportProperties `json:"properties"`
+
+	// Error describes why the input couldn't be evaluated, e.g. its
+	// namespace couldn't be derived. Such reports have no results.
+	Error string `json:"error,omitempty"`
+}
+
+// NewErrorReport returns the report of an input that couldn't be
+// evaluated because of err.
+func NewErrorReport(err error) Report {
+	return Report{
+		Rules: map[string]*Rule{},
+		Error: err.Error(),
+	}
 }
 
 func (r *Report) AddRule(rule *Rule) {
@@ -39,8 +70,114 @@ func (r *Report) AddRule(rule *Rule) {
 	r.Rules[rule.UID()] = rule
 }
 
+// AddResult adds a result to the report. A rule can have more
+// than one result, e.g. one for each violation it found.
 func (r *Report) AddResult(result *Result) {
-	r.Results[result.Rule.UID()] = result
+	r.Results = append(r.Results, result)
+}
+
+// ResultsByRule returns a result for each rule by rule UID, like
+// Results before rules could have more than one result. The result of
+// a rule is its first errored or failed result, if any, otherwise its
+// first result.
+func (r Report) ResultsByRule() map[string]*Result {
+	var (
+		results = map[string]*Result{}
+		failed  = func(r *Result) bool { return r.Errored() || r.Failed() }
+	)
+
+	for _, result := range r.Results {
+		uid := result.Rule.UID()
+
+		if existing, ok := results[uid]; !ok || (!failed(existing) && failed(result)) {
+			results[uid] = result
+		}
+	}
+
+	return results
+}
+
+// HasFailures returns true if any result in the report failed
+// and its rule causes a failure at threshold. See Rule.CausesFailureAt.
+func (r Report) HasFailures(threshold string) bool {
+	for _, result := range r.Results {
+		if result.Failed() && result.Rule.CausesFailureAt(threshold) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasErrors returns true if the input or any rule in
+// the report couldn't be evaluated.
+func (r Report) HasErrors() bool {
+	if r.Error != "" {
+		return true
+	}
+
+	for _, result := range r.Results {
+		if result.Errored() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reportJSON is the JSON representation of a Report.
+type reportJSON struct {
+	Rules      map[string]*Rule   `json:"rules"`
+	Results    map[string]*Result `json:"results"`
+	AllResults []*Result          `json:"allResults"`
+	RuleCount  int                `json:"ruleCount"`
+	Properties ReportProperties   `json:"properties"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes results by rule UID, like reports did before rules
+// could have more than one result, and every result in allResults.
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(reportJSON{
+		Rules:      r.Rules,
+		Results:    r.ResultsByRule(),
+		AllResults: r.Results,
+		RuleCount:  r.RuleCount,
+		Properties: r.Properties,
+		Error:      r.Error,
+	})
+}
+
+// UnmarshalJSON decodes a report encoded by MarshalJSON. Reports without
+// allResults get a result for each rule, sorted by rule UID.
+func (r *Report) UnmarshalJSON(data []byte) error {
+	var v reportJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*r = Report{
+		Rules:      v.Rules,
+		Results:    v.AllResults,
+		RuleCount:  v.RuleCount,
+		Properties: v.Properties,
+		Error:      v.Error,
+	}
+
+	if r.Results == nil && len(v.Results) > 0 {
+		uids := make([]string, 0, len(v.Results))
+		for uid := range v.Results {
+			uids = append(uids, uid)
+		}
+
+		sort.Strings(uids)
+
+		for _, uid := range uids {
+			r.Results = append(r.Results, v.Results[uid])
+		}
+	}
+
+	return nil
 }
 
 type ReportProperties map[string]interface{}
@@ -50,6 +187,40 @@ type Result struct {
 	Query   string `json:"query"`
 	Skipped bool   `json:"skipped"`
 	Passed  bool   `json:"passed"`
+
+	// Message describes a failed result. Set from the values produced
+	// by set-based rules, e.g. violation_foo[msg] { ... }.
+	Message string `json:"message,omitempty"`
+
+	// Location optionally identifies where the failure was found.
+	Location string `json:"location,omitempty"`
+
+	// Metadata holds any additional fields produced by the rule.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+
+	// Error describes why the rule couldn't be evaluated,
+	// e.g. it timed out.
+	Error string `json:"error,omitempty"`
+}
+
+// Failed returns true if the result was neither passed, skipped
+// nor errored.
+func (r Result) Failed() bool {
+	return !r.Passed && !r.Skipped && !r.Errored()
+}
+
+// Errored returns true if the rule couldn't be evaluated.
+func (r Result) Errored() bool {
+	return r.Error != ""
+}
+
+// Text returns the result message or, if empty, the rule title.
+func (r Result) Text() string {
+	if r.Message != "" {
+		return r.Message
+	}
+
+	return r.Rule.Title
 }
 
 type Rule struct {
@@ -63,34 +234,25 @@ type Rule struct {
 	Tags             []string `json:"tags"`
 }
 
-func NewRule(namespace string, rule *ast.Rule, as *ast.Annotations) (*Rule, error) {
-	headSplit := strings.SplitN(rule.Head.Name.String(), "_", 2)
-
-	if len(headSplit) != 2 {
-		return nil, fmt.Errorf("new rule: parse id: invalid rule name: %s", rule.Head.Name.String())
-	}
-
-	var (
-		kind     = headSplit[0]
-		id       = headSplit[1]
-		severity = ""
-	)
+// ErrNotRule is returned by NewRule for rules whose name doesn't
+// start with a known kind, e.g. helper rules.
+var ErrNotRule = errors.New("not a reposaur rule")
 
-	for sev, kinds := range SeverityRuleMap {
-		for _, k := range kinds {
-			if k == kind {
-				severity = sev
-				break
-			}
-		}
+// NewRule returns the rule in namespace named after its kind and ID, e.g.
+// violation_missing_description. Returns ErrNotRule if the name doesn't
+// start with a known kind. Returns any other error if the rule has a
+// known kind but its ID or annotations are invalid.
+func NewRule(namespace string, rule *ast.Rule, as *ast.Annotations) (*Rule, error) {
+	name := rule.Head.Name.String()
+	kind, id, _ := strings.Cut(name, "_")
 
-		if severity != "" {
-			break
-		}
+	severity := kindSeverity(kind)
+	if severity == "" {
+		return nil, fmt.Errorf("new rule: %s: %w", name, ErrNotRule)
 	}
 
-	if severity == "" {
-		return nil, fmt.Errorf("new rule: could not find severity for %s", kind)
+	if id == "" {
+		return nil, fmt.Errorf("new rule: parse id: invalid rule name: %s", name)
 	}
 
 	r := Rule{
@@ -116,8 +278,18 @@ func NewRule(namespace string, rule *ast.Rule, as *ast.Annotations) (*Rule, erro
 		}
 
 		if tags, ok := as.Custom["tags"]; ok {
-			for _, t := range tags.([]interface{}) {
-				r.Tags = append(r.Tags, t.(string))
+			list, ok := tags.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("new rule: %s: tags must be a list of strings", name)
+			}
+
+			for _, t := range list {
+				tag, ok := t.(string)
+				if !ok {
+					return nil, fmt.Errorf("new rule: %s: tags must be a list of strings", name)
+				}
+
+				r.Tags = append(r.Tags, tag)
 			}
 		}
 
@@ -129,8 +301,104 @@ func NewRule(namespace string, rule *ast.Rule, as *ast.Annotations) (*Rule, erro
 	return &r, nil
 }
 
+// kindSeverity returns the severity of rules of kind. Returns an
+// empty string if kind is unknown.
+func kindSeverity(kind string) string {
+	for sev, kinds := range SeverityRuleMap {
+		for _, k := range kinds {
+			if k == kind {
+				return sev
+			}
+		}
+	}
+
+	return ""
+}
+
+// MisspelledKind returns the known kind that the prefix of the rule
+// named name is likely a misspelling of, e.g. violation for
+// violaton_missing_description. Such rules are skipped by NewRule.
+func MisspelledKind(name string) (string, bool) {
+	prefix, _, ok := strings.Cut(name, "_")
+
+	// Short prefixes are usually helper rules, e.g. not_archived.
+	if !ok || len(prefix) < 4 || kindSeverity(prefix) != "" {
+		return "", false
+	}
+
+	var (
+		kind     string
+		distance = 3
+	)
+
+	for _, kinds := range SeverityRuleMap {
+		for _, k := range kinds {
+			if d := editDistance(prefix, k); d < distance || (d == distance && k < kind) {
+				kind, distance = k, d
+			}
+		}
+	}
+
+	return kind, kind != ""
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev = curr
+	}
+
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
+
 func (r Rule) CausesFailure() bool {
-	return r.Severity == ErrorSeverity
+	return r.CausesFailureAt(ErrorSeverity)
+}
+
+// CausesFailureAt returns true if the rule severity is at or
+// above the threshold severity.
+func (r Rule) CausesFailureAt(threshold string) bool {
+	level, ok := SeverityLevelMap[r.Severity]
+	if !ok {
+		return false
+	}
+
+	return level >= SeverityLevelMap[threshold]
+}
+
+// IsValidSeverity returns true if severity is one of
+// the known severities.
+func IsValidSeverity(severity string) bool {
+	_, ok := SeverityLevelMap[severity]
+	return ok
 }
 
 func (r Rule) UID() string {
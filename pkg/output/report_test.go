@@ -0,0 +1,199 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestCausesFailureAt(t *testing.T) {
+	testData := map[string]map[string]bool{
+		ErrorSeverity: {
+			ErrorSeverity:   true,
+			WarningSeverity: true,
+			NoteSeverity:    true,
+		},
+		WarningSeverity: {
+			ErrorSeverity:   false,
+			WarningSeverity: true,
+			NoteSeverity:    true,
+		},
+		NoteSeverity: {
+			ErrorSeverity:   false,
+			WarningSeverity: false,
+			NoteSeverity:    true,
+		},
+	}
+
+	for severity, thresholds := range testData {
+		rule := Rule{Severity: severity}
+
+		for threshold, expected := range thresholds {
+			if got := rule.CausesFailureAt(threshold); got != expected {
+				t.Errorf("expected %s rule failure at %s to be %t got %t", severity, threshold, expected, got)
+			}
+		}
+	}
+}
+
+func TestReportHasFailures(t *testing.T) {
+	warnRule := &Rule{ID: "foo", Kind: "warn", Severity: WarningSeverity, Namespace: "github.repository"}
+
+	report := Report{
+		Rules: map[string]*Rule{},
+	}
+
+	report.AddRule(warnRule)
+	report.AddResult(&Result{Rule: warnRule, Skipped: true})
+
+	if report.HasFailures(NoteSeverity) {
+		t.Fatal("expected skipped results to not cause failures")
+	}
+
+	report.AddResult(&Result{Rule: warnRule})
+
+	if report.HasFailures(ErrorSeverity) {
+		t.Fatal("expected warning results to not cause failures at error threshold")
+	}
+
+	if !report.HasFailures(WarningSeverity) {
+		t.Fatal("expected warning results to cause failures at warning threshold")
+	}
+}
+
+func TestReportResultsByRule(t *testing.T) {
+	var (
+		passRule = &Rule{ID: "foo", Kind: "warn", Severity: WarningSeverity, Namespace: "github.repository"}
+		failRule = &Rule{ID: "bar", Kind: "violation", Severity: ErrorSeverity, Namespace: "github.repository"}
+		report   = Report{Rules: map[string]*Rule{}}
+	)
+
+	report.AddRule(passRule)
+	report.AddRule(failRule)
+	report.AddResult(&Result{Rule: passRule, Passed: true})
+	report.AddResult(&Result{Rule: failRule, Passed: true})
+	report.AddResult(&Result{Rule: failRule, Message: "first"})
+	report.AddResult(&Result{Rule: failRule, Message: "second"})
+
+	results := report.ResultsByRule()
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results got %d", len(results))
+	}
+
+	if !results[passRule.UID()].Passed {
+		t.Fatalf("expected %s to pass", passRule.UID())
+	}
+
+	if r := results[failRule.UID()]; !r.Failed() || r.Message != "first" {
+		t.Fatalf("expected the first failed result of %s got %+v", failRule.UID(), r)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	var (
+		rule   = &Rule{ID: "bar", Kind: "violation", Severity: ErrorSeverity, Namespace: "github.repository"}
+		report = Report{Rules: map[string]*Rule{}}
+	)
+
+	report.AddRule(rule)
+	report.AddResult(&Result{Rule: rule, Message: "first"})
+	report.AddResult(&Result{Rule: rule, Message: "second"})
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Results keep their shape from before rules could have
+	// more than one result.
+	var doc struct {
+		Results    map[string]*Result `json:"results"`
+		AllResults []*Result          `json:"allResults"`
+	}
+
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := doc.Results[rule.UID()]; r == nil || r.Message != "first" {
+		t.Fatalf("expected the first result of %s got %+v", rule.UID(), r)
+	}
+
+	if len(doc.AllResults) != 2 {
+		t.Fatalf("expected 2 results in allResults got %d", len(doc.AllResults))
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded.Results) != 2 || decoded.Results[1].Message != "second" {
+		t.Fatalf("unexpected decoded results %+v", decoded.Results)
+	}
+}
+
+func TestNewRule(t *testing.T) {
+	tests := map[string]struct {
+		annotations string
+		expected    string
+	}{
+		"violation_missing_description": {},
+		"warn_topics":                   {annotations: "tags: [github, topics]"},
+		"helper":                        {expected: ErrNotRule.Error()},
+		"is_protected":                  {expected: ErrNotRule.Error()},
+		"violation":                     {expected: "invalid rule name"},
+		"violation_bad_tags":            {annotations: "tags: github", expected: "tags must be a list of strings"},
+		"violation_bad_tag":             {annotations: "tags: [1]", expected: "tags must be a list of strings"},
+	}
+
+	for name, tt := range tests {
+		src := "package github.repository\n\n"
+		if tt.annotations != "" {
+			src += "# METADATA\n# custom:\n#   " + tt.annotations + "\n"
+		}
+		src += name + " { true }\n"
+
+		mod, err := ast.ParseModuleWithOpts("test.rego", src, ast.ParserOptions{ProcessAnnotation: true})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		var as *ast.Annotations
+		if len(mod.Annotations) > 0 {
+			as = mod.Annotations[0]
+		}
+
+		_, err = NewRule("github.repository", mod.Rules[0], as)
+
+		if tt.expected == "" && err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Fatalf("%s: expected error '%s' got %v", name, tt.expected, err)
+		}
+	}
+}
+
+func TestMisspelledKind(t *testing.T) {
+	tests := map[string]string{
+		"violaton_missing_description": "violation",
+		"waring_missing_license":       "warn",
+		"errors_archived":              "error",
+		"violation_missing_license":    "",
+		"not_archived":                 "",
+		"has_license":                  "",
+		"description":                  "",
+	}
+
+	for name, expected := range tests {
+		kind, ok := MisspelledKind(name)
+		if ok != (expected != "") || kind != expected {
+			t.Errorf("%s: expected '%s' got '%s'", name, expected, kind)
+		}
+	}
+}
@@ -12,13 +12,51 @@ func NewSarifReport(report Report) (*sarif.Report, error) {
 		return nil, err
 	}
 
-	run := sarif.NewRunWithInformationURI("Reposaur", "https://github.com/reposaur/reposaur")
+	run := newSarifRun()
 
 	run.Properties = sarif.Properties{}
 	for k, v := range report.Properties {
 		run.Properties[k] = v
 	}
 
+	addSarifReport(run, report, false)
+
+	sr.AddRun(run)
+
+	return sr, nil
+}
+
+// NewCombinedSarifReport combines reports into a single SARIF run. Since
+// the run can't hold the properties of every report, they're added to the
+// properties of each result instead.
+func NewCombinedSarifReport(reports []Report) (*sarif.Report, error) {
+	sr, err := sarif.New(sarif.Version210)
+	if err != nil {
+		return nil, err
+	}
+
+	run := newSarifRun()
+
+	for _, report := range reports {
+		addSarifReport(run, report, true)
+	}
+
+	sr.AddRun(run)
+
+	return sr, nil
+}
+
+func newSarifRun() *sarif.Run {
+	return sarif.NewRunWithInformationURI("Reposaur", "https://github.com/reposaur/reposaur")
+}
+
+// addSarifReport adds the rules and failed results of report to run. If
+// withProperties is true, report properties are added to each result.
+func addSarifReport(run *sarif.Run, report Report, withProperties bool) {
+	if report.Error != "" {
+		addSarifNotification(run, report, report.Error, nil, withProperties)
+	}
+
 	for _, rule := range report.Rules {
 		props := sarif.Properties{}
 
@@ -42,23 +80,89 @@ func NewSarifReport(report Report) (*sarif.Report, error) {
 	}
 
 	for _, result := range report.Results {
-		if !result.Passed && !result.Skipped {
-			run.AddResult(sarif.NewRuleResult(result.Rule.UID()).
-				WithLevel(strings.ToLower(result.Rule.Severity)).
-				WithMessage(sarif.NewTextMessage(result.Rule.Title)).
-				WithLocations([]*sarif.Location{
-					sarif.NewLocation().WithPhysicalLocation(
-						sarif.NewPhysicalLocation().
-							WithArtifactLocation(
-								sarif.NewSimpleArtifactLocation("."),
-							),
-					),
-				}),
-			)
+		if result.Errored() {
+			addSarifNotification(run, report, result.Error, result, withProperties)
+			continue
+		}
+
+		if !result.Failed() {
+			continue
+		}
+
+		location := "."
+		if result.Location != "" {
+			location = result.Location
 		}
+
+		sarifResult := sarif.NewRuleResult(result.Rule.UID()).
+			WithLevel(strings.ToLower(result.Rule.Severity)).
+			WithMessage(sarif.NewTextMessage(result.Text())).
+			WithLocations([]*sarif.Location{
+				sarif.NewLocation().WithPhysicalLocation(
+					sarif.NewPhysicalLocation().
+						WithArtifactLocation(
+							sarif.NewSimpleArtifactLocation(location),
+						),
+				),
+			})
+
+		props := sarif.NewPropertyBag()
+
+		if withProperties {
+			for k, v := range report.Properties {
+				props.Add(k, v)
+			}
+		}
+
+		for k, v := range result.Metadata {
+			props.Add(k, v)
+		}
+
+		if len(props.Properties) > 0 {
+			sarifResult.AttachPropertyBag(props)
+		}
+
+		run.AddResult(sarifResult)
 	}
+}
 
-	sr.AddRun(run)
+// addSarifNotification adds an error as a tool execution notification, marking
+// the run invocation as unsuccessful. The error is either of result, whose rule
+// couldn't be evaluated, or of the input of report if result is nil. If
+// withProperties is true, report properties are added to the notification.
+func addSarifNotification(run *sarif.Run, report Report, message string, result *Result, withProperties bool) {
+	if len(run.Invocations) == 0 {
+		run.AddInvocation(false)
+	}
 
-	return sr, nil
+	invocation := run.Invocations[0]
+
+	successful := false
+	invocation.ExecutionSuccessful = &successful
+
+	notification := sarif.NewNotification().
+		WithLevel("error").
+		WithTextMessage(message)
+
+	props := sarif.NewPropertyBag()
+
+	if result != nil {
+		notification.WithAssociatedRule(sarif.NewReportingDescriptorReference().WithId(result.Rule.UID()))
+
+		if result.Query != "" {
+			props.Add("query", result.Query)
+		}
+	}
+
+	if withProperties {
+		for k, v := range report.Properties {
+			props.Add(k, v)
+		}
+	}
+
+	if len(props.Properties) > 0 {
+		notification.Properties = props.Properties
+	}
+
+	invocation.AddTToolExecutionNotification(notification)
 }
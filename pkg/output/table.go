@@ -0,0 +1,170 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorBlue   = "\x1b[34m"
+	colorGray   = "\x1b[90m"
+	colorBold   = "\x1b[1m"
+)
+
+var severityColorMap = map[string]string{
+	ErrorSeverity:   colorRed,
+	WarningSeverity: colorYellow,
+	NoteSeverity:    colorBlue,
+}
+
+// tableEncoder buffers every report and, when closed, writes a summary
+// of the failed results grouped by namespace and severity.
+type tableEncoder struct {
+	w       io.Writer
+	colors  bool
+	records []ResultRecord
+}
+
+func (e *tableEncoder) Encode(report Report) error {
+	for _, result := range report.Results {
+		e.records = append(e.records, NewResultRecord(report, result))
+	}
+
+	return nil
+}
+
+func (e *tableEncoder) Close() error {
+	var (
+		namespaces []string
+		byNs       = map[string][]ResultRecord{}
+	)
+
+	for _, r := range e.records {
+		if _, ok := byNs[r.Namespace]; !ok {
+			namespaces = append(namespaces, r.Namespace)
+		}
+
+		byNs[r.Namespace] = append(byNs[r.Namespace], r)
+	}
+
+	sort.Strings(namespaces)
+
+	var failed, errored, passed, skipped int
+
+	for _, ns := range namespaces {
+		records := byNs[ns]
+
+		sort.SliceStable(records, func(i, j int) bool {
+			a, b := records[i], records[j]
+
+			if a.Severity != b.Severity {
+				return SeverityLevelMap[a.Severity] > SeverityLevelMap[b.Severity]
+			}
+
+			if a.RuleID != b.RuleID {
+				return a.RuleID < b.RuleID
+			}
+
+			return formatProperties(a.Properties) < formatProperties(b.Properties)
+		})
+
+		var nsFailed, nsErrored, nsPassed, nsSkipped int
+
+		tw := tabwriter.NewWriter(e.w, 0, 0, 2, ' ', 0)
+
+		for _, r := range records {
+			switch {
+			case r.Skipped:
+				nsSkipped++
+				continue
+
+			case r.Passed:
+				nsPassed++
+				continue
+			}
+
+			message := r.Message
+
+			if r.Error != "" {
+				nsErrored++
+				message = "error: " + r.Error
+			} else {
+				nsFailed++
+			}
+
+			if nsFailed+nsErrored == 1 {
+				if _, err := fmt.Fprintf(e.w, "%s\n", e.color(colorBold, ns)); err != nil {
+					return err
+				}
+			}
+
+			_, err := fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n",
+				e.color(severityColorMap[r.Severity], strings.ToUpper(r.Severity)),
+				strings.TrimPrefix(r.RuleID, ns+"/"),
+				formatProperties(r.Properties),
+				message,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		if nsFailed+nsErrored > 0 {
+			if _, err := fmt.Fprintln(e.w); err != nil {
+				return err
+			}
+		}
+
+		failed += nsFailed
+		errored += nsErrored
+		passed += nsPassed
+		skipped += nsSkipped
+	}
+
+	summary := []string{e.color(colorRed, fmt.Sprintf("%d failed", failed))}
+
+	// Errors are rare, only mention them when there are any.
+	if errored > 0 {
+		summary = append(summary, e.color(colorRed, fmt.Sprintf("%d errored", errored)))
+	}
+
+	summary = append(summary,
+		e.color(colorGreen, fmt.Sprintf("%d passed", passed)),
+		e.color(colorGray, fmt.Sprintf("%d skipped", skipped)),
+	)
+
+	_, err := fmt.Fprintln(e.w, strings.Join(summary, ", "))
+
+	return err
+}
+
+func (e *tableEncoder) color(color, s string) string {
+	if !e.colors || color == "" {
+		return s
+	}
+
+	return color + s + colorReset
+}
+
+// formatProperties returns the properties as sorted key=value pairs.
+func formatProperties(props ReportProperties) string {
+	pairs := make([]string, 0, len(props))
+	for k, v := range props {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,392 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/compile"
+	"github.com/open-policy-agent/opa/format"
+	"github.com/reposaur/reposaur/internal/policy"
+	"github.com/reposaur/reposaur/pkg/output"
+)
+
+// BundleOption represents an option that changes how
+// bundles are built.
+type BundleOption func(*bundleOptions)
+
+type bundleOptions struct {
+	revision     string
+	minVersion   string
+	optimization int
+	signing      *bundle.SigningConfig
+	keyID        string
+}
+
+// WithRevision sets the revision in the bundle manifest.
+func WithRevision(revision string) BundleOption {
+	return func(o *bundleOptions) {
+		o.revision = revision
+	}
+}
+
+// WithMinVersion sets the minimum version of Reposaur
+// required to load the bundle.
+func WithMinVersion(version string) BundleOption {
+	return func(o *bundleOptions) {
+		o.minVersion = version
+	}
+}
+
+// WithOptimization sets the optimization level of the bundle. Rules are
+// optimized using partial evaluation. Zero disables optimizations.
+func WithOptimization(level int) BundleOption {
+	return func(o *bundleOptions) {
+		o.optimization = level
+	}
+}
+
+// WithSigningKey signs bundles with key, either a PEM encoded private key,
+// a secret or a path to a file with one of them. Signatures are written
+// to .signatures.json and identify the key with keyID.
+func WithSigningKey(key, alg, keyID string) BundleOption {
+	return func(o *bundleOptions) {
+		o.signing = bundle.NewSigningConfig(key, alg, "")
+		o.keyID = keyID
+	}
+}
+
+// Bundle builds a new OCI-compatible policy bundle from the policies
+// sdk was created with. Rules are validated before building the bundle.
+//
+// The bundle manifest roots are the namespaces of the policies and its
+// metadata includes the providers used by the policies and, if optimized,
+// the annotations of rules. See policy.Metadata.
+func (sdk Reposaur) Bundle(ctx context.Context, out io.Writer, opts ...BundleOption) error {
+	var o bundleOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	metadata := policy.Metadata{
+		MinVersion: o.minVersion,
+		Providers:  sdk.usedProviders(),
+	}
+
+	// Fail early rather than building a bundle that can't be loaded.
+	if err := metadata.Validate(); err != nil {
+		return fmt.Errorf("build bundle: %w", err)
+	}
+
+	rules, err := sdk.engine.Rules()
+	if err != nil {
+		return err
+	}
+
+	sdk.warnMisspelledRules()
+
+	c := compile.New().
+		WithTarget(compile.TargetRego).
+		WithEnablePrintStatements(true).
+		WithPaths(sdk.engine.Paths()...)
+
+	if o.optimization > 0 {
+		c = c.WithOptimizationLevel(o.optimization).
+			WithEntrypoints(sdk.entrypoints(rules)...)
+	}
+
+	if err := c.Build(ctx); err != nil {
+		return fmt.Errorf("build bundle: %w", err)
+	}
+
+	b := c.Bundle()
+
+	roots := bundleRoots(b, sdk.engine.Namespaces())
+	b.Manifest.Roots = &roots
+	b.Manifest.Revision = o.revision
+
+	// Optimizations rewrite rules without their METADATA comments,
+	// so their annotations are kept in the metadata instead.
+	if o.optimization > 0 {
+		if err := dropDanglingAnnotations(b); err != nil {
+			return fmt.Errorf("build bundle: %w", err)
+		}
+
+		metadata.Annotations = ruleAnnotations(sdk.engine.Modules())
+	}
+
+	if md := metadata.Map(); len(md) > 0 {
+		b.Manifest.Metadata = map[string]interface{}{
+			policy.MetadataKey: md,
+		}
+	}
+
+	// The manifest must be set before the bundle is signed,
+	// since it's one of the signed files.
+	c = compile.New().
+		WithTarget(compile.TargetRego).
+		WithEnablePrintStatements(true).
+		WithBundle(b).
+		WithOutput(out)
+
+	if o.signing != nil {
+		c = c.WithBundleSigningConfig(o.signing).
+			WithBundleVerificationKeyID(o.keyID)
+	}
+
+	if err := c.Build(ctx); err != nil {
+		return fmt.Errorf("write bundle: %w", err)
+	}
+
+	return nil
+}
+
+// entrypoints returns the rules and skip documents of every
+// namespace, in the form path/to/namespace/rule.
+func (sdk Reposaur) entrypoints(rules []*output.Rule) []string {
+	var entrypoints []string
+
+	for _, r := range rules {
+		entrypoints = append(entrypoints, namespacePath(r.Namespace)+"/"+r.Kind+"_"+r.ID)
+	}
+
+	for _, mod := range sdk.engine.Modules() {
+		for _, r := range mod.Rules {
+			if r.Head.Name.String() == "skip" {
+				namespace := strings.TrimPrefix(mod.Package.Path.String(), "data.")
+				entrypoints = append(entrypoints, namespacePath(namespace)+"/skip")
+				break
+			}
+		}
+	}
+
+	return entrypoints
+}
+
+// usedProviders returns the providers whose builtins are called by
+// the policies. Providers are named after the prefix of their
+// builtins, e.g. github for github.request.
+func (sdk Reposaur) usedProviders() []string {
+	builtins := map[string]string{}
+
+	for _, p := range sdk.providers {
+		for _, b := range p.Builtins() {
+			name := b.Func().Name
+			if i := strings.Index(name, "."); i > 0 {
+				builtins[name] = name[:i]
+			}
+		}
+	}
+
+	used := map[string]bool{}
+
+	for _, mod := range sdk.engine.Compiler().Modules {
+		ast.WalkExprs(mod, func(expr *ast.Expr) bool {
+			if expr.IsCall() {
+				if p, ok := builtins[expr.Operator().String()]; ok {
+					used[p] = true
+				}
+			}
+
+			return false
+		})
+	}
+
+	providers := make([]string, 0, len(used))
+	for p := range used {
+		providers = append(providers, p)
+	}
+
+	sort.Strings(providers)
+
+	return providers
+}
+
+// bundleRoots returns the roots of b, i.e. the paths of namespaces, of
+// the packages added by optimizations and of its data documents.
+// Roots contained in other roots are omitted, since roots can't overlap.
+func bundleRoots(b *bundle.Bundle, namespaces []string) []string {
+	var paths []string
+
+	for _, ns := range namespaces {
+		paths = append(paths, namespacePath(ns))
+	}
+
+	for _, mod := range b.Modules {
+		paths = append(paths, namespacePath(strings.TrimPrefix(mod.Parsed.Package.Path.String(), "data.")))
+	}
+
+	for k := range b.Data {
+		paths = append(paths, k)
+	}
+
+	// Shorter paths first, so they're kept over the paths they contain.
+	sort.Slice(paths, func(i, j int) bool {
+		return len(paths[i]) < len(paths[j]) || (len(paths[i]) == len(paths[j]) && paths[i] < paths[j])
+	})
+
+	var roots []string
+	for _, p := range paths {
+		if !bundle.RootPathsContain(roots, p) {
+			roots = append(roots, p)
+		}
+	}
+
+	sort.Strings(roots)
+
+	return roots
+}
+
+// ruleAnnotations returns the annotations of the rules
+// in modules by rule path. See policy.Metadata.
+func ruleAnnotations(modules map[string]*ast.Module) map[string]policy.RuleAnnotations {
+	annotations := map[string]policy.RuleAnnotations{}
+
+	for _, mod := range modules {
+		for _, a := range mod.Annotations {
+			if a.Scope == "rule" {
+				annotations[a.GetTargetPath().String()] = policy.NewRuleAnnotations(a)
+			}
+		}
+	}
+
+	return annotations
+}
+
+// dropDanglingAnnotations removes the METADATA comments left behind by
+// optimizations in the modules of b, which remove rules from the modules
+// they keep without their comments. Otherwise the bundle can't be parsed.
+// Modules with dangling comments are formatted again without them.
+func dropDanglingAnnotations(b *bundle.Bundle) error {
+	for i, mf := range b.Modules {
+		// Modules written by optimizations have no comments.
+		if mf.Parsed == nil || len(mf.Parsed.Comments) == 0 {
+			continue
+		}
+
+		comments := attachedComments(mf.Parsed)
+		if len(comments) == len(mf.Parsed.Comments) {
+			continue
+		}
+
+		mf.Parsed.Comments = comments
+
+		raw, err := format.Ast(mf.Parsed)
+		if err != nil {
+			return fmt.Errorf("format %s: %w", mf.Path, err)
+		}
+
+		b.Modules[i].Raw = raw
+	}
+
+	return nil
+}
+
+// attachedComments returns the comments of mod without the METADATA
+// blocks that don't precede a statement, i.e. those followed by
+// another METADATA block or by the end of the module.
+func attachedComments(mod *ast.Module) []*ast.Comment {
+	var rows []int
+
+	rows = append(rows, mod.Package.Location.Row)
+	for _, imp := range mod.Imports {
+		rows = append(rows, imp.Location.Row)
+	}
+	for _, r := range mod.Rules {
+		rows = append(rows, r.Location.Row)
+	}
+
+	comments := make([]*ast.Comment, len(mod.Comments))
+	copy(comments, mod.Comments)
+
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].Location.Row < comments[j].Location.Row
+	})
+
+	var (
+		kept   []*ast.Comment
+		blocks []int
+	)
+
+	for i, c := range comments {
+		if isMetadataComment(c) {
+			blocks = append(blocks, i)
+		}
+	}
+
+	start := 0
+	for n, b := range blocks {
+		kept = append(kept, comments[start:b]...)
+
+		// The block ends at the first comment that isn't on the
+		// row following the previous one.
+		end := b + 1
+		for end < len(comments) && !isMetadataComment(comments[end]) &&
+			comments[end].Location.Row == comments[end-1].Location.Row+1 {
+			end++
+		}
+
+		next := -1
+		if n+1 < len(blocks) {
+			next = comments[blocks[n+1]].Location.Row
+		}
+
+		if precedesStatement(comments[end-1].Location.Row, next, rows) {
+			kept = append(kept, comments[b:end]...)
+		}
+
+		start = end
+	}
+
+	return append(kept, comments[start:]...)
+}
+
+// precedesStatement reports whether any of the statements at rows starts
+// after row and before next. A negative next means the end of the module.
+func precedesStatement(row, next int, rows []int) bool {
+	for _, r := range rows {
+		if r > row && (next < 0 || r < next) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isMetadataComment(c *ast.Comment) bool {
+	return strings.TrimSpace(string(c.Text)) == "METADATA"
+}
+
+// namespacePath returns namespace as a bundle path,
+// e.g. github/repository for github.repository.
+func namespacePath(namespace string) string {
+	return strings.ReplaceAll(namespace, ".", "/")
+}
+
+// warnMisspelledRules logs a warning for every rule whose kind looks
+// misspelled, since these are skipped instead of being evaluated.
+func (sdk Reposaur) warnMisspelledRules() {
+	seen := map[string]bool{}
+
+	for _, mod := range sdk.engine.Modules() {
+		for _, r := range mod.Rules {
+			path := r.Path().String()
+			if seen[path] {
+				continue
+			}
+
+			seen[path] = true
+
+			if kind, ok := output.MisspelledKind(r.Head.Name.String()); ok {
+				sdk.logger.Warn().
+					Str("rule", path).
+					Str("location", r.Location.String()).
+					Msgf("rule name looks like a misspelling of %s, it won't be evaluated", kind)
+			}
+		}
+	}
+}
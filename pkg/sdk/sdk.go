@@ -5,14 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/open-policy-agent/opa/compile"
+	"github.com/open-policy-agent/opa/bundle"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/tester"
 	"github.com/open-policy-agent/opa/topdown"
+	"github.com/reposaur/reposaur/internal/oci"
 	"github.com/reposaur/reposaur/internal/policy"
 	"github.com/reposaur/reposaur/pkg/output"
 	"github.com/reposaur/reposaur/provider"
@@ -25,6 +26,10 @@ var DefaultProviders = []provider.Provider{
 	github.NewProvider(nil),
 }
 
+// ErrNoPolicies is returned by CheckNamespace when no policy is
+// loaded in the namespace, usually because of a misspelled name.
+var ErrNoPolicies = errors.New("no policies in namespace")
+
 // Option represents a Reposaur option that can change a
 // particular behavior.
 type Option func(*Reposaur)
@@ -37,6 +42,18 @@ type Reposaur struct {
 	engine        *policy.Engine
 	providers     []provider.Provider
 	enableTracing bool
+	writeAccess   bool
+	dryRun        bool
+	ruleTimeout   time.Duration
+	inputTimeout  time.Duration
+	concurrency   int
+	dataPaths     []string
+
+	bundleCacheDir   string
+	registryUsername string
+	registryPassword string
+	verification     *bundle.VerificationConfig
+	skipVerify       bool
 }
 
 // New returns a new Reposaur instance, loading and
@@ -72,11 +89,49 @@ func New(ctx context.Context, policyPaths []string, opts ...Option) (*Reposaur,
 	}
 
 	var err error
-	sdk.engine, err = policy.Load(ctx, policyPaths, policy.WithTracingEnabled(sdk.enableTracing))
+	engineOpts := []policy.Option{
+		policy.WithTracingEnabled(sdk.enableTracing),
+		policy.WithRuleTimeout(sdk.ruleTimeout),
+		policy.WithInputTimeout(sdk.inputTimeout),
+	}
+
+	if sdk.concurrency > 0 {
+		engineOpts = append(engineOpts, policy.WithConcurrency(sdk.concurrency))
+	}
+
+	if len(sdk.dataPaths) > 0 {
+		engineOpts = append(engineOpts, policy.WithData(sdk.dataPaths))
+	}
+
+	if sdk.bundleCacheDir != "" {
+		engineOpts = append(engineOpts, policy.WithBundleCacheDir(sdk.bundleCacheDir))
+	}
+
+	if sdk.verification != nil {
+		engineOpts = append(engineOpts, policy.WithBundleVerification(sdk.verification))
+	}
+
+	if sdk.skipVerify {
+		engineOpts = append(engineOpts, policy.WithSkipBundleVerification(true))
+	}
+
+	if sdk.registryUsername != "" {
+		engineOpts = append(engineOpts, policy.WithOCIClient(oci.NewClient(sdk.registryUsername, sdk.registryPassword)))
+	}
+
+	sdk.engine, err = policy.Load(ctx, policyPaths, engineOpts...)
 	if err != nil {
 		return nil, err
 	}
 
+	for path, m := range sdk.engine.Manifests() {
+		sdk.logger.Debug().
+			Str("bundle", path).
+			Str("revision", m.Revision).
+			Strs("roots", *m.Roots).
+			Msg("loaded bundle")
+	}
+
 	return sdk, nil
 }
 
@@ -102,6 +157,88 @@ func WithTracingEnabled(enabled bool) Option {
 	}
 }
 
+// WithRuleTimeout sets the maximum duration of a rule evaluation.
+// Rules that time out produce an error result. Zero means no timeout.
+func WithRuleTimeout(d time.Duration) Option {
+	return func(sdk *Reposaur) {
+		sdk.ruleTimeout = d
+	}
+}
+
+// WithInputTimeout sets the maximum duration of the evaluation of every
+// rule against an input. Rules not evaluated in time produce an error
+// result. Zero means no timeout.
+func WithInputTimeout(d time.Duration) Option {
+	return func(sdk *Reposaur) {
+		sdk.inputTimeout = d
+	}
+}
+
+// WithConcurrency sets the maximum number of rules evaluated
+// concurrently against an input. Defaults to the number of CPUs.
+func WithConcurrency(n int) Option {
+	return func(sdk *Reposaur) {
+		sdk.concurrency = n
+	}
+}
+
+// WithData loads the JSON and YAML documents at paths, either files
+// or directories, so that policies can reference them under data.
+func WithData(paths []string) Option {
+	return func(sdk *Reposaur) {
+		sdk.dataPaths = paths
+	}
+}
+
+// WithBundleCacheDir sets the directory where bundles fetched from
+// HTTP(S) URLs or OCI registries are cached.
+func WithBundleCacheDir(dir string) Option {
+	return func(sdk *Reposaur) {
+		sdk.bundleCacheDir = dir
+	}
+}
+
+// WithRegistryCredentials sets the credentials used to fetch bundles
+// from OCI registries.
+func WithRegistryCredentials(username, password string) Option {
+	return func(sdk *Reposaur) {
+		sdk.registryUsername = username
+		sdk.registryPassword = password
+	}
+}
+
+// WithBundleVerification sets the keys used to verify the signatures
+// of bundles. If set, only signed bundles are loaded.
+func WithBundleVerification(config *bundle.VerificationConfig) Option {
+	return func(sdk *Reposaur) {
+		sdk.verification = config
+	}
+}
+
+// WithSkipBundleVerification disables the verification of the
+// signatures of bundles.
+func WithSkipBundleVerification(skip bool) Option {
+	return func(sdk *Reposaur) {
+		sdk.skipVerify = skip
+	}
+}
+
+// WithWriteAccess allows builtins to send requests that modify
+// data, e.g. to remediate violations. Disabled by default.
+func WithWriteAccess(enabled bool) Option {
+	return func(sdk *Reposaur) {
+		sdk.writeAccess = enabled
+	}
+}
+
+// WithDryRun makes builtins log the requests that would modify
+// data instead of sending them. Takes precedence over WithWriteAccess.
+func WithDryRun(enabled bool) Option {
+	return func(sdk *Reposaur) {
+		sdk.dryRun = enabled
+	}
+}
+
 // Logger returns Reposaur logger.
 func (sdk Reposaur) Logger() zerolog.Logger {
 	return sdk.logger
@@ -112,31 +249,36 @@ func (sdk Reposaur) Engine() *policy.Engine {
 	return sdk.engine
 }
 
-// Check executes the policies loaded against data. Data is checked against every
-// provider to derive a namespace and additional report properties.
-func (sdk Reposaur) Check(ctx context.Context, data interface{}) (output.Report, error) {
-	var (
-		dataProvider provider.Provider
-		namespace    provider.Namespace
-		err          error
-	)
+// HasNamespace reports whether any loaded policy is in namespace.
+func (sdk Reposaur) HasNamespace(namespace string) bool {
+	for _, ns := range sdk.engine.Namespaces() {
+		if ns == namespace {
+			return true
+		}
+	}
 
-	for _, p := range sdk.providers {
-		namespace, err = provider.DeriveNamespace(p, data)
-		if err != nil {
-			if errors.Is(err, provider.ErrNonDerivable) {
-				continue
-			}
+	return false
+}
 
-			return output.Report{}, err
+// Check executes the policies loaded against data. Data is scored against every
+// provider to derive the most specific namespace and additional report properties.
+// If data matches more than one namespace equally, returns a
+// *provider.AmbiguousNamespaceError.
+func (sdk Reposaur) Check(ctx context.Context, data interface{}) (output.Report, error) {
+	ctx = sdk.evalContext(ctx)
+
+	dataProvider, namespace, candidates, err := provider.Derive(sdk.providers, data)
+	if err != nil {
+		if errors.Is(err, provider.ErrNonDerivable) {
+			return output.Report{}, errors.New("could not derive a valid namespace from data")
 		}
 
-		dataProvider = p
+		return output.Report{}, err
 	}
 
-	if dataProvider == nil {
-		return output.Report{}, errors.New("could not derive a valid namespace from data")
-	}
+	sdk.logger.Debug().
+		Interface("candidates", candidates).
+		Msgf("derived namespace %s", namespace)
 
 	report, err := sdk.engine.Check(ctx, string(namespace), data)
 	if err != nil {
@@ -151,11 +293,75 @@ func (sdk Reposaur) Check(ctx context.Context, data interface{}) (output.Report,
 	return report, nil
 }
 
+// CheckNamespace executes the policies in namespace against data, skipping
+// namespace derivation. Useful when data doesn't have the shape providers
+// expect, e.g. trimmed GraphQL results or custom exports. Report properties
+// are derived by the first provider able to do so.
+//
+// Returns an error wrapping ErrNoPolicies if no policy is loaded in namespace.
+func (sdk Reposaur) CheckNamespace(ctx context.Context, namespace string, data interface{}) (output.Report, error) {
+	if !sdk.HasNamespace(namespace) {
+		return output.Report{}, fmt.Errorf("%w %s", ErrNoPolicies, namespace)
+	}
+
+	ctx = sdk.evalContext(ctx)
+
+	report, err := sdk.engine.Check(ctx, namespace, data)
+	if err != nil {
+		return output.Report{}, err
+	}
+
+	for _, p := range sdk.providers {
+		props, err := provider.DeriveProperties(p, provider.Namespace(namespace), data)
+		if err != nil {
+			if errors.Is(err, provider.ErrNonDerivable) {
+				continue
+			}
+
+			return output.Report{}, err
+		}
+
+		report.Properties = props
+		break
+	}
+
+	return report, nil
+}
+
+// Test runs the policy tests. Tests that can't be evaluated, e.g.
+// because of a conflict error, are returned with their error.
+//
+// If the policies define a mocks document at provider.MocksPath, builtins
+// return the mocked responses instead of sending any request (see
+// provider.Mocks). For example:
+//
+//	package reposaur
+//
+//	mocks["GET /repos/{owner}/{repo}/branches"] := {"status": 200, "body": [{"name": "main"}]}
+//	mocks["GET /repos/reposaur/missing/branches"] := {"status": 404, "body": {"message": "Not Found"}}
+//
+// Mocks are supported by the builtins of every provider. They're evaluated
+// once, before running the tests, so replacing them in a test case (e.g.
+// `with data.reposaur.mocks as ...`) has no effect. Instead, test cases can
+// replace a builtin with a function or value using the with keyword, e.g.
+// `with github.request as mock_request`.
 func (sdk Reposaur) Test(ctx context.Context) ([]*tester.Result, error) {
+	ctx = sdk.evalContext(ctx)
+
+	mocks, err := sdk.mocks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if mocks != nil {
+		ctx = provider.NewMockContext(ctx, mocks)
+	}
+
 	runner := tester.NewRunner().
 		EnableTracing(sdk.enableTracing).
 		CapturePrintOutput(true).
 		SetCompiler(sdk.engine.Compiler()).
+		SetStore(sdk.engine.Store()).
 		SetModules(sdk.engine.Modules())
 
 	ch, err := runner.RunTests(ctx, nil)
@@ -165,10 +371,6 @@ func (sdk Reposaur) Test(ctx context.Context) ([]*tester.Result, error) {
 
 	var rawResults []*tester.Result
 	for result := range ch {
-		if result.Error != nil {
-			return nil, fmt.Errorf("run test: %w", result.Error)
-		}
-
 		rawResults = append(rawResults, result)
 		buf := new(bytes.Buffer)
 		topdown.PrettyTrace(buf, result.Trace)
@@ -190,16 +392,42 @@ func (sdk Reposaur) Test(ctx context.Context) ([]*tester.Result, error) {
 	return rawResults, nil
 }
 
-// Bundle builds a new OCI-compatible policy bundle.
-func (sdk Reposaur) Bundle(ctx context.Context, paths []string, out io.Writer) error {
-	c := compile.New().
-		WithOutput(out).
-		WithTarget("rego").
-		WithPaths(paths...)
+// evalContext returns a context carrying the logger and write mode
+// used by builtins during evaluation.
+func (sdk Reposaur) evalContext(ctx context.Context) context.Context {
+	mode := provider.WriteDenied
+
+	switch {
+	case sdk.dryRun:
+		mode = provider.WriteDryRun
+	case sdk.writeAccess:
+		mode = provider.WriteAllowed
+	}
+
+	ctx = sdk.logger.WithContext(ctx)
+
+	return provider.NewWriteModeContext(ctx, mode)
+}
+
+// mocks returns the mocks document defined by the policies, if any.
+func (sdk Reposaur) mocks(ctx context.Context) (provider.Mocks, error) {
+	rs, err := rego.New(
+		rego.Query(provider.MocksPath),
+		rego.Compiler(sdk.engine.Compiler()),
+		rego.Store(sdk.engine.Store()),
+	).Eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate mocks: %w", err)
+	}
+
+	if len(rs) == 0 {
+		return nil, nil
+	}
 
-	if err := c.Build(ctx); err != nil {
-		return err
+	mocks, ok := rs[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object", provider.MocksPath)
 	}
 
-	return nil
+	return mocks, nil
 }
@@ -0,0 +1,350 @@
+package sdk_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/keys"
+
+	"github.com/reposaur/reposaur/internal/policy"
+	"github.com/reposaur/reposaur/pkg/sdk"
+	"github.com/rs/zerolog"
+)
+
+func TestTestMocks(t *testing.T) {
+	ctx := context.Background()
+
+	rsr, err := sdk.New(ctx, []string{"testdata/mocks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := rsr.Test(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("expected 4 test results got %d", len(results))
+	}
+
+	for _, r := range results {
+		if r.Fail || r.Error != nil {
+			t.Errorf("expected test %s to pass: %v", r.Name, r.Error)
+		}
+	}
+}
+
+func TestTestErrors(t *testing.T) {
+	ctx := context.Background()
+
+	rsr, err := sdk.New(ctx, []string{"testdata/errors"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := rsr.Test(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errored := map[string]bool{}
+	for _, r := range results {
+		errored[r.Name] = r.Error != nil
+	}
+
+	expected := map[string]bool{
+		"test_visibility":          false,
+		"test_visibility_conflict": true,
+	}
+
+	if !reflect.DeepEqual(errored, expected) {
+		t.Fatalf("expected errored tests %v got %v", expected, errored)
+	}
+}
+
+func TestBundleSigning(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		privPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		pubPEM  = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})
+		path    = filepath.Join(t.TempDir(), "bundle.tar.gz")
+	)
+
+	rsr, err := sdk.New(ctx, []string{"testdata/mocks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rsr.Bundle(ctx, out, sdk.WithSigningKey(string(privPEM), "RS256", "ci")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	verification := bundle.NewVerificationConfig(map[string]*keys.Config{
+		"ci": {Key: string(pubPEM), Algorithm: "RS256"},
+	}, "", "", nil)
+
+	if _, err := sdk.New(ctx, []string{path}, sdk.WithBundleVerification(verification)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signatures identify the key they were signed with.
+	verification.PublicKeys = map[string]*keys.Config{
+		"other": {Key: string(pubPEM), Algorithm: "RS256"},
+	}
+
+	if _, err := sdk.New(ctx, []string{path}, sdk.WithBundleVerification(verification)); err == nil {
+		t.Fatal("expected an error verifying the bundle with an unknown key ID")
+	}
+}
+
+func TestBundleManifest(t *testing.T) {
+	ctx := context.Background()
+
+	for _, level := range []int{0, 1} {
+		t.Run(fmt.Sprintf("optimize=%d", level), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+
+			rsr, err := sdk.New(ctx, []string{"testdata/bundle"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := []sdk.BundleOption{
+				sdk.WithRevision("abc123"),
+				sdk.WithMinVersion("0.1.0"),
+				sdk.WithOptimization(level),
+			}
+
+			if err := rsr.Bundle(ctx, out, opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := out.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			rsr, err = sdk.New(ctx, []string{path})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m, ok := rsr.Engine().Manifests()[path]
+			if !ok {
+				t.Fatalf("expected a manifest for %s", path)
+			}
+
+			if m.Revision != "abc123" {
+				t.Fatalf("expected revision 'abc123' got '%s'", m.Revision)
+			}
+
+			if m.Roots == nil || !bundle.RootPathsContain(*m.Roots, "github/repository") {
+				t.Fatalf("expected roots to contain 'github/repository' got %v", m.Roots)
+			}
+
+			md, err := policy.ManifestMetadata(m)
+			if err != nil || md == nil {
+				t.Fatalf("expected reposaur metadata got %v", err)
+			}
+
+			if md.MinVersion != "0.1.0" {
+				t.Fatalf("expected min version '0.1.0' got '%s'", md.MinVersion)
+			}
+
+			if len(md.Providers) != 1 || md.Providers[0] != "github" {
+				t.Fatalf("expected providers [github] got %v", md.Providers)
+			}
+
+			rules, err := rsr.Engine().Rules()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			titles := map[string]string{}
+			for _, r := range rules {
+				titles[r.ID] = r.Title
+			}
+
+			expected := map[string]string{
+				"missing_description":        "Repository is missing a description",
+				"unprotected_default_branch": "Default branch isn't protected",
+			}
+
+			for id, title := range expected {
+				if titles[id] != title {
+					t.Fatalf("expected rule %s to have title '%s' got '%s'", id, title, titles[id])
+				}
+			}
+
+			// Annotations other than those of rules are kept.
+			packageTitle := ""
+			for _, mod := range rsr.Engine().Modules() {
+				for _, a := range mod.Annotations {
+					if a.Scope == "package" {
+						packageTitle = a.Title
+					}
+				}
+			}
+
+			if packageTitle != "Repository policies" {
+				t.Fatalf("expected package annotations with title 'Repository policies' got '%s'", packageTitle)
+			}
+
+			report, err := rsr.CheckNamespace(ctx, "github.repository", map[string]interface{}{"archived": true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			skipped := false
+			for _, r := range report.Results {
+				if r.Rule.ID == "missing_description" {
+					skipped = r.Skipped
+				}
+			}
+
+			if !skipped {
+				t.Fatal("expected missing_description to be skipped for archived repositories")
+			}
+		})
+	}
+}
+
+func TestCheckNamespaceWithoutPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	rsr, err := sdk.New(ctx, []string{"testdata/data/policy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = rsr.CheckNamespace(ctx, "github.repositroy", map[string]interface{}{})
+	if !errors.Is(err, sdk.ErrNoPolicies) {
+		t.Fatalf("expected ErrNoPolicies got %v", err)
+	}
+}
+
+func TestBundleInvalidMinVersion(t *testing.T) {
+	ctx := context.Background()
+
+	rsr, err := sdk.New(ctx, []string{"testdata/bundle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rsr.Bundle(ctx, io.Discard, sdk.WithMinVersion("latest"))
+	if err == nil || !strings.Contains(err.Error(), "invalid minimum version 'latest'") {
+		t.Fatalf("expected an invalid minimum version error got %v", err)
+	}
+}
+
+func TestBundleMisspelledRules(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		logs bytes.Buffer
+	)
+
+	rsr, err := sdk.New(ctx, []string{"testdata/misspelled"}, sdk.WithLogger(zerolog.New(&logs)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rsr.Bundle(ctx, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(logs.String(), "data.github.repository.violaton_missing_description") {
+		t.Fatalf("expected a warning for violaton_missing_description got %s", logs.String())
+	}
+
+	if strings.Contains(logs.String(), "not_archived") {
+		t.Fatalf("expected no warning for not_archived got %s", logs.String())
+	}
+}
+
+func TestBundleInvalidRules(t *testing.T) {
+	ctx := context.Background()
+
+	rsr, err := sdk.New(ctx, []string{"testdata/invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rsr.Bundle(ctx, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "tags must be a list of strings") {
+		t.Fatalf("expected an invalid tags error got %v", err)
+	}
+}
+
+func TestData(t *testing.T) {
+	ctx := context.Background()
+
+	rsr, err := sdk.New(ctx, []string{"testdata/data/policy"}, sdk.WithData([]string{"testdata/data/documents"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := rsr.CheckNamespace(ctx, "github.repository", map[string]interface{}{
+		"license": map[string]interface{}{"spdx_id": "gpl-3.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Results) != 1 || !report.Results[0].Failed() {
+		t.Fatalf("expected license_not_allowed to fail got %+v", report.Results)
+	}
+
+	// Tests can override the data documents.
+	results, err := rsr.Test(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 test results got %d", len(results))
+	}
+
+	for _, r := range results {
+		if r.Fail || r.Error != nil {
+			t.Errorf("expected test %s to pass: %v", r.Name, r.Error)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+# METADATA
+# scope: package
+# title: Repository policies
+package github.repository
+
+# METADATA
+# description: Login of the repository owner, used by other policies.
+owner := input.owner.login
+
+skip[rule] {
+	input.archived
+	rule := ["missing_description"]
+}
+
+# METADATA
+# title: Repository is missing a description
+# description: Repositories should describe what they're for.
+# custom:
+#   tags: [documentation]
+warn_missing_description {
+	not input.description
+}
+
+# METADATA
+# title: Default branch isn't protected
+violation_unprotected_default_branch {
+	resp := github.request("GET /repos/{owner}/{repo}/branches/{branch}", {
+		"owner": input.owner.login,
+		"repo": input.name,
+		"branch": input.default_branch,
+	})
+
+	not resp.body.protected
+}
@@ -0,0 +1,9 @@
+package github.repository
+
+import future.keywords.in
+
+# METADATA
+# title: Repository license isn't allowed
+violation_license_not_allowed {
+	not input.license.spdx_id in data.allowed_licenses
+}
@@ -0,0 +1,16 @@
+package github.repository
+
+repo(license) := {"license": {"spdx_id": license}}
+
+test_allowed_license {
+	not violation_license_not_allowed with input as repo("mit")
+}
+
+test_license_not_allowed {
+	violation_license_not_allowed with input as repo("gpl-3.0")
+}
+
+test_overridden_licenses {
+	violation_license_not_allowed with input as repo("mit")
+		with data.allowed_licenses as ["gpl-3.0"]
+}
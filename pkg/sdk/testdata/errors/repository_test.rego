@@ -0,0 +1,17 @@
+package github.repository
+
+visibility = "public" {
+	input.private == false
+}
+
+visibility = "private" {
+	input.name == "reposaur"
+}
+
+test_visibility {
+	visibility == "public" with input as {"private": false, "name": "other"}
+}
+
+test_visibility_conflict {
+	visibility == "public" with input as {"private": false, "name": "reposaur"}
+}
@@ -0,0 +1,9 @@
+package github.repository
+
+# METADATA
+# title: Repository is missing a description
+# custom:
+#   tags: documentation
+warn_missing_description {
+	not input.description
+}
@@ -0,0 +1,14 @@
+package github.repository
+
+violaton_missing_description {
+	not input.description
+}
+
+not_archived {
+	not input.archived
+}
+
+warn_missing_license {
+	not_archived
+	not input.license
+}
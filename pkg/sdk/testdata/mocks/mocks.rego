@@ -0,0 +1,16 @@
+package reposaur
+
+mocks["GET /repos/{owner}/{repo}/branches"] := {
+	"status": 200,
+	"body": [{"name": "main"}],
+}
+
+mocks["GET /repos/reposaur/missing/branches"] := {
+	"status": 404,
+	"body": {"message": "Not Found"},
+}
+
+mocks["GET /repos/reposaur/private/branches"] := {
+	"status": 403,
+	"body": {"message": "Resource not accessible by integration"},
+}
@@ -0,0 +1,17 @@
+package github.repository
+
+import future.keywords.in
+
+branches := github.request("GET /repos/{owner}/{repo}/branches", {
+	"owner": input.owner.login,
+	"repo": input.name,
+})
+
+violation_missing_main_branch {
+	branches.status == 200
+	not "main" in {b.name | some b in branches.body}
+}
+
+violation_repository_not_found {
+	branches.status == 404
+}
@@ -0,0 +1,22 @@
+package github.repository
+
+repo(name) := {"owner": {"login": "reposaur"}, "name": name}
+
+test_main_branch {
+	not violation_missing_main_branch with input as repo("reposaur")
+	not violation_repository_not_found with input as repo("reposaur")
+}
+
+test_not_found {
+	violation_repository_not_found with input as repo("missing")
+}
+
+test_forbidden {
+	not branches with input as repo("private")
+}
+
+mock_request(_, _) := {"status": 200, "body": [{"name": "dev"}]}
+
+test_with_mocked_builtin {
+	violation_missing_main_branch with input as repo("reposaur") with github.request as mock_request
+}
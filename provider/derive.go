@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Candidate is a namespace matched by some data, along with a score
+// representing how specific the match is. Higher scores are more specific.
+type Candidate struct {
+	Namespace Namespace `json:"namespace"`
+	Score     int       `json:"score"`
+}
+
+// NamespaceScorer is the interface implemented by data derivers that can
+// score every namespace matched by data, instead of returning a single one.
+// Scores allow picking the most specific namespace across providers.
+type NamespaceScorer interface {
+	ScoreNamespaces(map[string]any) []Candidate
+}
+
+// AmbiguousNamespaceError is returned when data matches more than one
+// namespace with the same score.
+type AmbiguousNamespaceError struct {
+	Namespaces []Namespace
+}
+
+func (e *AmbiguousNamespaceError) Error() string {
+	names := make([]string, len(e.Namespaces))
+	for i, ns := range e.Namespaces {
+		names[i] = string(ns)
+	}
+
+	return fmt.Sprintf("data is ambiguous, matches namespaces: %s", strings.Join(names, ", "))
+}
+
+// ScoreKeys returns a candidate for every namespace in namespaceToKeys whose
+// keys are all present in data. Each candidate is scored with the number
+// of keys of its namespace, so namespaces requiring more keys are considered
+// more specific. Candidates are sorted by score and then by namespace.
+func ScoreKeys(namespaceToKeys map[Namespace][]string, data map[string]any) []Candidate {
+	var candidates []Candidate
+
+	for namespace, keys := range namespaceToKeys {
+		if len(keys) == 0 {
+			continue
+		}
+
+		matches := 0
+		for _, key := range keys {
+			if _, ok := data[key]; ok {
+				matches++
+			}
+		}
+
+		if matches == len(keys) {
+			candidates = append(candidates, Candidate{
+				Namespace: namespace,
+				Score:     matches,
+			})
+		}
+	}
+
+	sortCandidates(candidates)
+
+	return candidates
+}
+
+// BestCandidate returns the namespace of the highest scoring candidate. If
+// there are no candidates returns ErrNonDerivable, if more than one candidate
+// has the highest score returns an *AmbiguousNamespaceError.
+func BestCandidate(candidates []Candidate) (Namespace, error) {
+	if len(candidates) == 0 {
+		return "", ErrNonDerivable
+	}
+
+	sortCandidates(candidates)
+
+	best := []Namespace{candidates[0].Namespace}
+	for _, c := range candidates[1:] {
+		if c.Score != candidates[0].Score {
+			break
+		}
+
+		best = append(best, c.Namespace)
+	}
+
+	if len(best) > 1 {
+		return "", &AmbiguousNamespaceError{Namespaces: best}
+	}
+
+	return best[0], nil
+}
+
+// Derive scores data against every provider and returns the most specific
+// namespace along with the provider it belongs to. Providers that don't
+// implement NamespaceScorer have their derived namespace scored with 1.
+//
+// The returned candidates include every namespace matched by data, which is
+// useful to explain why a namespace was chosen.
+func Derive(providers []Provider, data any) (Provider, Namespace, []Candidate, error) {
+	m, err := dataToMap(data)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var (
+		candidates []Candidate
+		owners     = map[Namespace]Provider{}
+	)
+
+	for _, p := range providers {
+		var pCandidates []Candidate
+
+		if scorer, ok := p.(NamespaceScorer); ok {
+			pCandidates = scorer.ScoreNamespaces(m)
+		} else {
+			namespace, err := p.DeriveNamespace(m)
+			if err != nil {
+				if errors.Is(err, ErrNonDerivable) {
+					continue
+				}
+
+				return nil, "", nil, err
+			}
+
+			pCandidates = []Candidate{{Namespace: namespace, Score: 1}}
+		}
+
+		for _, c := range pCandidates {
+			if _, ok := owners[c.Namespace]; ok {
+				continue
+			}
+
+			owners[c.Namespace] = p
+			candidates = append(candidates, c)
+		}
+	}
+
+	namespace, err := BestCandidate(candidates)
+	if err != nil {
+		return nil, "", candidates, err
+	}
+
+	return owners[namespace], namespace, candidates, nil
+}
+
+func sortCandidates(candidates []Candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+
+		return candidates[i].Namespace < candidates[j].Namespace
+	})
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+)
+
+type keysProvider map[Namespace][]string
+
+func (p keysProvider) DeriveNamespace(data map[string]any) (Namespace, error) {
+	return BestCandidate(p.ScoreNamespaces(data))
+}
+
+func (p keysProvider) ScoreNamespaces(data map[string]any) []Candidate {
+	return ScoreKeys(p, data)
+}
+
+func (p keysProvider) DeriveProperties(Namespace, map[string]any) (map[string]any, error) {
+	return nil, ErrNonDerivable
+}
+
+func (p keysProvider) Builtins() []Builtin {
+	return nil
+}
+
+type fixedProvider Namespace
+
+func (p fixedProvider) DeriveNamespace(map[string]any) (Namespace, error) {
+	return Namespace(p), nil
+}
+
+func (p fixedProvider) DeriveProperties(Namespace, map[string]any) (map[string]any, error) {
+	return nil, ErrNonDerivable
+}
+
+func (p fixedProvider) Builtins() []Builtin {
+	return nil
+}
+
+func TestScoreKeysRequiresEveryKey(t *testing.T) {
+	candidates := ScoreKeys(map[Namespace][]string{
+		"a": {"foo", "bar"},
+		"b": {"foo", "baz"},
+	}, map[string]any{"foo": 1, "bar": 2})
+
+	if len(candidates) != 1 || candidates[0].Namespace != "a" || candidates[0].Score != 2 {
+		t.Fatalf("unexpected candidates %v", candidates)
+	}
+}
+
+func TestDerivePicksMostSpecificAcrossProviders(t *testing.T) {
+	providers := []Provider{
+		keysProvider{"github.repository": {"owner", "full_name"}},
+		keysProvider{"gitea.repository": {"owner", "full_name", "internal_tracker"}},
+	}
+
+	data := map[string]any{
+		"owner":            "reposaur",
+		"full_name":        "reposaur/reposaur",
+		"internal_tracker": map[string]any{},
+	}
+
+	// Run several times, map iteration order must not change the outcome
+	for i := 0; i < 10; i++ {
+		p, namespace, candidates, err := Derive(providers, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if namespace != "gitea.repository" {
+			t.Fatalf("expected namespace to be 'gitea.repository' got '%s'", namespace)
+		}
+
+		if p.(keysProvider)["gitea.repository"] == nil {
+			t.Fatalf("expected the Gitea provider to be returned")
+		}
+
+		if len(candidates) != 2 {
+			t.Fatalf("expected 2 candidates got %d", len(candidates))
+		}
+	}
+}
+
+func TestDeriveAmbiguous(t *testing.T) {
+	providers := []Provider{
+		keysProvider{"b.user": {"login", "name"}},
+		keysProvider{"a.user": {"login", "email"}},
+		fixedProvider("c.other"),
+	}
+
+	_, _, _, err := Derive(providers, map[string]any{
+		"login": "crqra",
+		"name":  "Cristian",
+		"email": "crqra@example.com",
+	})
+
+	var ambiguousErr *AmbiguousNamespaceError
+	if !errors.As(err, &ambiguousErr) {
+		t.Fatalf("expected an ambiguity error got '%v'", err)
+	}
+
+	if len(ambiguousErr.Namespaces) != 2 || ambiguousErr.Namespaces[0] != "a.user" || ambiguousErr.Namespaces[1] != "b.user" {
+		t.Fatalf("unexpected ambiguous namespaces %v", ambiguousErr.Namespaces)
+	}
+}
+
+func TestDeriveNonDerivable(t *testing.T) {
+	_, _, _, err := Derive([]Provider{keysProvider{"a": {"foo"}}}, map[string]any{"bar": 1})
+	if !errors.Is(err, ErrNonDerivable) {
+		t.Errorf("expected error '%s' got '%s'", ErrNonDerivable, err)
+	}
+}
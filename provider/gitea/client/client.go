@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	DefaultBaseURL = "https://gitea.com/api/v1"
+)
+
+type Client struct {
+	BaseURL *url.URL
+
+	client *retryablehttp.Client
+	token  string
+}
+
+func NewClient(httpClient *http.Client) *Client {
+	baseURL, _ := url.Parse(DefaultBaseURL)
+	client := newRetryableClient(httpClient)
+
+	return &Client{
+		BaseURL: baseURL,
+		client:  client,
+	}
+}
+
+// NewTokenClient returns a client that authenticates every request
+// with an access token. Works with both Gitea and Forgejo instances.
+func NewTokenClient(_ context.Context, token string) *Client {
+	client := NewClient(nil)
+	client.token = token
+
+	return client
+}
+
+func (c Client) Client() *http.Client {
+	return c.client.HTTPClient
+}
+
+func (c Client) Token(_ context.Context) (string, error) {
+	return c.token, nil
+}
+
+// NewRequest creates a new request relative to the API base URL. The
+// API is served under a path (e.g. /api/v1), so path is appended to it
+// instead of resolved against it.
+func (c Client) NewRequest(method, path string, rawBody any) (*retryablehttp.Request, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.BaseURL.String(), "/") + "/" + strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := retryablehttp.NewRequest(method, u.String(), rawBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if rawBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "token "+c.token)
+	}
+
+	req.Header.Set("User-Agent", "reposaur")
+
+	return req, nil
+}
+
+func (c Client) Do(req *retryablehttp.Request) (*http.Response, error) {
+	return c.client.Do(req)
+}
+
+func newRetryableClient(httpClient *http.Client) *retryablehttp.Client {
+	client := retryablehttp.NewClient()
+
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	}
+
+	return client
+}
@@ -0,0 +1,137 @@
+package gitea
+
+import (
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/gitea/client"
+	"github.com/reposaur/reposaur/provider/gitea/internal/builtin"
+)
+
+const (
+	IssueNamespace        provider.Namespace = "gitea.issue"
+	OrganizationNamespace provider.Namespace = "gitea.organization"
+	PullRequestNamespace  provider.Namespace = "gitea.pull_request"
+	RepositoryNamespace   provider.Namespace = "gitea.repository"
+	UserNamespace         provider.Namespace = "gitea.user"
+)
+
+type Gitea struct {
+	client      *client.Client
+	dataDeriver *DataDeriver
+	builtins    []provider.Builtin
+}
+
+func NewProvider(c *client.Client) *Gitea {
+	if c == nil {
+		c = client.NewClient(nil)
+	}
+
+	return &Gitea{
+		client: c,
+		builtins: []provider.Builtin{
+			&builtin.Request{Client: c},
+		},
+		dataDeriver: &DataDeriver{
+			namespaceToKeys: map[provider.Namespace][]string{
+				IssueNamespace:        {"original_author", "assets"},
+				OrganizationNamespace: {"username", "repo_admin_change_team_access"},
+				PullRequestNamespace:  {"base", "head", "merge_base"},
+				RepositoryNamespace:   {"owner", "full_name", "internal_tracker"},
+				UserNamespace:         {"login", "login_name"},
+			},
+		},
+	}
+}
+
+func (g Gitea) DeriveNamespace(data map[string]any) (provider.Namespace, error) {
+	return g.dataDeriver.DeriveNamespace(data)
+}
+
+func (g Gitea) ScoreNamespaces(data map[string]any) []provider.Candidate {
+	return g.dataDeriver.ScoreNamespaces(data)
+}
+
+func (g Gitea) DeriveProperties(namespace provider.Namespace, data map[string]any) (map[string]any, error) {
+	return g.dataDeriver.DeriveProperties(namespace, data)
+}
+
+func (g Gitea) Builtins() []provider.Builtin {
+	return g.builtins
+}
+
+type DataDeriver struct {
+	namespaceToKeys map[provider.Namespace][]string
+}
+
+func (d DataDeriver) DeriveNamespace(data map[string]any) (provider.Namespace, error) {
+	return provider.BestCandidate(d.ScoreNamespaces(data))
+}
+
+// ScoreNamespaces returns every namespace whose keys are all present in
+// data, scored by the number of keys. See provider.ScoreKeys.
+func (d DataDeriver) ScoreNamespaces(data map[string]any) []provider.Candidate {
+	return provider.ScoreKeys(d.namespaceToKeys, data)
+}
+
+func (d DataDeriver) DeriveProperties(namespace provider.Namespace, data map[string]any) (map[string]any, error) {
+	switch namespace {
+	case IssueNamespace, PullRequestNamespace:
+		props := map[string]any{}
+
+		if id, ok := data["id"]; ok {
+			props["id"] = id
+		}
+
+		if nr, ok := data["number"]; ok {
+			props["number"] = nr
+		}
+
+		return props, nil
+
+	case OrganizationNamespace:
+		props := map[string]any{}
+
+		if username, ok := data["username"]; ok {
+			props["login"] = username
+		}
+
+		if name, ok := data["full_name"]; ok {
+			props["name"] = name
+		}
+
+		return props, nil
+
+	case UserNamespace:
+		props := map[string]any{}
+
+		if login, ok := data["login"]; ok {
+			props["login"] = login
+		}
+
+		if name, ok := data["full_name"]; ok {
+			props["name"] = name
+		}
+
+		return props, nil
+
+	case RepositoryNamespace:
+		props := map[string]any{}
+
+		if owner, ok := data["owner"].(map[string]any); ok {
+			if login, ok := owner["login"]; ok {
+				props["owner"] = login
+			}
+		}
+
+		if name, ok := data["name"]; ok {
+			props["repo"] = name
+		}
+
+		if defaultBranch, ok := data["default_branch"]; ok {
+			props["default_branch"] = defaultBranch
+		}
+
+		return props, nil
+	}
+
+	return nil, provider.ErrNonDerivable
+}
@@ -0,0 +1,155 @@
+package gitea_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/gitea"
+	"github.com/reposaur/reposaur/provider/gitea/client"
+)
+
+func TestDeriveNamespace(t *testing.T) {
+	g := gitea.NewProvider(nil)
+
+	testData := map[provider.Namespace]map[string]any{
+		gitea.IssueNamespace: {
+			"original_author": "",
+			"assets":          []any{},
+		},
+		gitea.OrganizationNamespace: {
+			"username":                      "reposaur",
+			"repo_admin_change_team_access": false,
+		},
+		gitea.PullRequestNamespace: {
+			"base":       map[string]any{},
+			"head":       map[string]any{},
+			"merge_base": "0a1b2c",
+		},
+		gitea.RepositoryNamespace: {
+			"owner":            map[string]any{"login": "reposaur"},
+			"full_name":        "reposaur/reposaur",
+			"internal_tracker": map[string]any{},
+		},
+		gitea.UserNamespace: {
+			"login":      "crqra",
+			"login_name": "",
+		},
+	}
+
+	for expected, data := range testData {
+		namespace, err := g.DeriveNamespace(data)
+		if err != nil {
+			t.Fatalf("testing %s: %s", expected, err)
+		}
+
+		if namespace != expected {
+			t.Fatalf("expected namespace to be '%s' got '%s'", expected, namespace)
+		}
+	}
+}
+
+func TestRequestBuiltin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/reposaur/reposaur/branches" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"not found"}`))
+			return
+		}
+
+		if got := r.Header.Get("Authorization"); got != "token secret" {
+			t.Errorf("expected Authorization header 'token secret' got '%s'", got)
+		}
+
+		if got := r.URL.Query().Get("limit"); got != "50" {
+			t.Errorf("expected limit query parameter '50' got '%s'", got)
+		}
+
+		_ = json.NewEncoder(w).Encode([]map[string]any{{"name": "main"}})
+	}))
+	defer srv.Close()
+
+	c := client.NewTokenClient(context.Background(), "secret")
+	c.BaseURL, _ = url.Parse(srv.URL + "/api/v1")
+
+	opts := []func(*rego.Rego){
+		rego.Query(`resp := gitea.request("GET /repos/{owner}/{repo}/branches", {"owner": "reposaur", "repo": "reposaur", "limit": 50})`),
+	}
+
+	for _, b := range gitea.NewProvider(c).Builtins() {
+		opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+	}
+
+	rs, err := rego.New(opts...).Eval(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := rs[0].Bindings["resp"].(map[string]any)
+
+	if status := resp["status"]; status != json.Number("200") {
+		t.Fatalf("expected status 200 got %v", status)
+	}
+
+	branches := resp["body"].([]any)
+	if len(branches) != 1 || branches[0].(map[string]any)["name"] != "main" {
+		t.Fatalf("unexpected body: %v", resp["body"])
+	}
+}
+
+func TestRequestBuiltinMocks(t *testing.T) {
+	mocks := provider.Mocks{
+		"GET /repos/{owner}/{repo}/branches": map[string]any{"body": []any{map[string]any{"name": "main"}}},
+		"GET /repos/reposaur/private/branches": map[string]any{
+			"status": json.Number("403"),
+			"body":   map[string]any{"message": "token does not have at least one of required scope(s)"},
+		},
+	}
+
+	tests := map[string]string{
+		"reposaur": "",
+		"private":  "forbidden: token does not have at least one of required scope(s)",
+	}
+
+	// No server is running, mocked requests are never sent.
+	c := client.NewTokenClient(context.Background(), "secret")
+	c.BaseURL, _ = url.Parse("http://127.0.0.1:0/api/v1")
+
+	for repo, expected := range tests {
+		opts := []func(*rego.Rego){
+			rego.Query(fmt.Sprintf(`resp := gitea.request("GET /repos/{owner}/{repo}/branches", {"owner": "reposaur", "repo": %q})`, repo)),
+			rego.StrictBuiltinErrors(true),
+		}
+
+		for _, b := range gitea.NewProvider(c).Builtins() {
+			opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+		}
+
+		rs, err := rego.New(opts...).Eval(provider.NewMockContext(context.Background(), mocks))
+
+		if expected != "" {
+			if err == nil || !strings.Contains(err.Error(), expected) {
+				t.Fatalf("%s: expected error '%s' got %v", repo, expected, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", repo, err)
+		}
+
+		resp := rs[0].Bindings["resp"].(map[string]any)
+
+		if status := resp["status"]; status != json.Number("200") {
+			t.Fatalf("%s: expected status 200 got %v", repo, status)
+		}
+	}
+}
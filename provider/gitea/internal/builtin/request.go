@@ -0,0 +1,90 @@
+package builtin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/gitea/client"
+)
+
+type Request struct {
+	Client *client.Client
+}
+
+func (r Request) Func() *rego.Function {
+	return &rego.Function{
+		Name: "gitea.request",
+		Decl: types.NewFunction(
+			types.Args(
+				types.S,
+				types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)),
+			),
+			types.A,
+		),
+		Memoize: true,
+	}
+}
+
+func (r Request) Impl(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+	req, mockKeys, err := r.argsToRequest(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	finalResp, err := send(bctx.Context, r.Client, req, mockKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := ast.InterfaceToValue(finalResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewTerm(val), nil
+}
+
+// argsToRequest returns the request in terms, along
+// with the keys of the mocks matching it.
+func (r Request) argsToRequest(terms []*ast.Term) (*retryablehttp.Request, []string, error) {
+	// FIXME: Function receives 2 arguments but terms includes one additional at last index
+	if len(terms) != 3 {
+		return nil, nil, fmt.Errorf("wrong number of arguments, expected 2 got %d", len(terms)-1)
+	}
+
+	var (
+		path string
+		data map[string]any
+	)
+
+	if err := ast.As(terms[0].Value, &path); err != nil {
+		return nil, nil, err
+	}
+
+	if err := ast.As(terms[1].Value, &data); err != nil {
+		return nil, nil, err
+	}
+
+	pr, err := provider.ParseRequest(path, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if pr.Method != http.MethodGet {
+		return nil, nil, fmt.Errorf("only GET requests are supported, got '%s'", pr.Method)
+	}
+
+	u, err := pr.URL()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := r.Client.NewRequest(pr.Method, u, nil)
+
+	return req, pr.MockKeys(), err
+}
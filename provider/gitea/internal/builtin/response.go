@@ -0,0 +1,41 @@
+package builtin
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/gitea/client"
+)
+
+type response struct {
+	StatusCode int         `json:"status"`
+	Body       interface{} `json:"body"`
+}
+
+// send sends req using c and decodes its response.
+//
+// If ctx carries mocks, req isn't sent and the response is the first
+// mock matching mockKeys instead.
+func send(ctx context.Context, c *client.Client, req *retryablehttp.Request, mockKeys ...string) (*response, error) {
+	if mock, mocked, err := provider.MockedResponse(ctx, mockKeys...); mocked {
+		if err != nil {
+			return nil, err
+		}
+
+		return &response{StatusCode: mock.StatusCode, Body: mock.Body}, nil
+	}
+
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := provider.DecodeBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response{StatusCode: resp.StatusCode, Body: body}, nil
+}
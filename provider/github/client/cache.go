@@ -0,0 +1,164 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// DiskCache stores GET responses in a directory so they can be
+// revalidated with conditional requests, which don't count
+// against GitHub's rate limit.
+type DiskCache struct {
+	Dir string
+}
+
+// cacheEntry is a cached response.
+type cacheEntry struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// NewDiskCache returns a cache that stores responses in dir,
+// creating it if needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	return &DiskCache{Dir: dir}, nil
+}
+
+func (dc DiskCache) get(key string) (*cacheEntry, bool) {
+	b, err := os.ReadFile(dc.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+func (dc DiskCache) set(key string, entry *cacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// Entries are written to a temporary file first so concurrent
+	// readers never see a partially written entry.
+	f, err := os.CreateTemp(dc.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), dc.path(key))
+}
+
+func (dc DiskCache) path(key string) string {
+	return filepath.Join(dc.Dir, key+".json")
+}
+
+// response builds a response from the cached entry, using the
+// request and protocol of the revalidation response.
+func (e *cacheEntry) response(revalidated *http.Response) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         revalidated.Proto,
+		ProtoMajor:    revalidated.ProtoMajor,
+		ProtoMinor:    revalidated.ProtoMinor,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       revalidated.Request,
+	}
+}
+
+// cacheKey returns the key of req's response. Responses are only
+// shared between clients with the same identity.
+func (c Client) cacheKey(req *retryablehttp.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s", c.identity, req.URL.String(), req.Header.Get("Accept"))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// doCached sends req, revalidating the cached response if one exists.
+// Successful responses with an ETag or Last-Modified header are cached.
+func (c Client) doCached(req *retryablehttp.Request) (*http.Response, error) {
+	var (
+		key        = c.cacheKey(req)
+		entry, hit = c.Cache.get(key)
+		revalidate = hit && req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == ""
+	)
+
+	if revalidate {
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if revalidate && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return entry.response(resp), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	if resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Failing to cache a response shouldn't fail the request,
+	// it'll be fetched again next time.
+	_ = c.Cache.set(key, &cacheEntry{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	})
+
+	return resp, nil
+}
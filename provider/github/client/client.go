@@ -2,8 +2,10 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/hashicorp/go-retryablehttp"
@@ -17,18 +19,39 @@ const (
 type Client struct {
 	BaseURL *url.URL
 
+	// Cache stores GET responses to revalidate them in later
+	// requests. Caching is disabled if nil.
+	Cache *DiskCache
+
+	// Fixtures records every request and response, or replays them
+	// without sending any request. Disabled if nil.
+	Fixtures *Fixtures
+
 	client       *retryablehttp.Client
+	writeClient  *retryablehttp.Client
 	appTransport *ghinstallation.Transport
 	token        string
+	rateLimiter  *rateLimiter
+
+	// identity distinguishes the cached responses of each
+	// authenticated client.
+	identity string
 }
 
 func NewClient(httpClient *http.Client) *Client {
 	baseURL, _ := url.Parse(DefaultBaseURL)
-	client := newRetryableClient(httpClient)
+	rateLimiter := newRateLimiter()
+	client := newRetryableClient(httpClient, rateLimiter, rateLimiter.checkRetry)
+
+	// Requests that modify data share the underlying client, but
+	// aren't retried unless GitHub didn't process them.
+	writeClient := newRetryableClient(client.HTTPClient, rateLimiter, rateLimiter.checkWriteRetry)
 
 	return &Client{
-		BaseURL: baseURL,
-		client:  client,
+		BaseURL:     baseURL,
+		client:      client,
+		writeClient: writeClient,
+		rateLimiter: rateLimiter,
 	}
 }
 
@@ -41,6 +64,7 @@ func NewTokenClient(ctx context.Context, token string) *Client {
 
 	client := NewClient(oauthClient)
 	client.token = token
+	client.identity = "token:" + token
 
 	return client
 }
@@ -51,7 +75,11 @@ func NewAppClient(_ context.Context, baseURL string, appID, installationID int64
 		return nil, err
 	}
 
-	appTransport.BaseURL = baseURL
+	// Installation tokens are requested from the
+	// same API, e.g. GitHub Enterprise Server.
+	if baseURL != "" {
+		appTransport.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
 
 	httpClient := &http.Client{
 		Transport: appTransport,
@@ -59,6 +87,7 @@ func NewAppClient(_ context.Context, baseURL string, appID, installationID int64
 
 	client := NewClient(httpClient)
 	client.appTransport = appTransport
+	client.identity = fmt.Sprintf("app:%d:%d", appID, installationID)
 
 	return client, nil
 }
@@ -95,12 +124,64 @@ func (c Client) NewRequest(method, path string, rawBody any) (*retryablehttp.Req
 	return req, nil
 }
 
+// Do sends req, waiting first if the rate limit of its resource was
+// exhausted. Rate-limited responses are retried once the limit resets.
+// Other failures, e.g. server errors, are only retried if req is
+// idempotent, so that requests modifying data are sent at most once.
+// If c.Cache is set, GET requests are revalidated against it. If c.Fixtures
+// is set, requests are recorded (bypassing the cache) or replayed.
 func (c Client) Do(req *retryablehttp.Request) (*http.Response, error) {
-	return c.client.Do(req)
+	client := c.retryableClient(req)
+
+	if c.Fixtures != nil && c.Fixtures.Replaying() {
+		return c.Fixtures.do(client, req)
+	}
+
+	if err := c.rateLimiter.wait(req); err != nil {
+		return nil, err
+	}
+
+	if c.Fixtures != nil {
+		return c.Fixtures.do(client, req)
+	}
+
+	if c.Cache != nil && req.Method == http.MethodGet {
+		return c.doCached(req)
+	}
+
+	return client.Do(req)
+}
+
+// retryableClient returns the client used to send req, depending on
+// whether it's safe to retry. See NewIdempotentContext.
+func (c Client) retryableClient(req *retryablehttp.Request) *retryablehttp.Client {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return c.client
+	}
+
+	if isIdempotent(req.Context()) {
+		return c.client
+	}
+
+	return c.writeClient
+}
+
+// RateLimit returns the last known rate-limit state of resource.
+func (c Client) RateLimit(resource string) (RateLimit, bool) {
+	return c.rateLimiter.get(resource)
+}
+
+// RateLimits returns the last known rate-limit state of every
+// resource used by the client.
+func (c Client) RateLimits() map[string]RateLimit {
+	return c.rateLimiter.all()
 }
 
-func newRetryableClient(httpClient *http.Client) *retryablehttp.Client {
+func newRetryableClient(httpClient *http.Client, rateLimiter *rateLimiter, checkRetry retryablehttp.CheckRetry) *retryablehttp.Client {
 	client := retryablehttp.NewClient()
+	client.CheckRetry = checkRetry
+	client.Backoff = rateLimiter.backoff
 
 	if httpClient != nil {
 		client.HTTPClient = httpClient
@@ -0,0 +1,386 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reposaur/reposaur/provider/github/client"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *client.Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c := client.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL)
+
+	return c
+}
+
+func doGet(t *testing.T, c *client.Client, path string) *http.Response {
+	req, err := c.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Do(req.WithContext(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	return resp
+}
+
+func TestRateLimitState(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Used", "1")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		w.Header().Set("X-RateLimit-Resource", "core")
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	if _, ok := c.RateLimit(client.CoreResource); ok {
+		t.Fatal("expected rate limit to be unknown before any request")
+	}
+
+	doGet(t, c, "/repos/reposaur/reposaur")
+
+	l, ok := c.RateLimit(client.CoreResource)
+	if !ok {
+		t.Fatal("expected rate limit to be known")
+	}
+
+	if l.Limit != 5000 || l.Remaining != 4999 || l.Used != 1 || l.Reset.Unix() != reset {
+		t.Fatalf("unexpected rate limit: %+v", l)
+	}
+}
+
+func TestPrimaryRateLimitWait(t *testing.T) {
+	var (
+		calls int32
+		reset = time.Now().Add(2 * time.Second).Unix()
+	)
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		remaining := "0"
+		if atomic.AddInt32(&calls, 1) > 1 {
+			remaining = "4999"
+		}
+
+		w.Header().Set("X-RateLimit-Remaining", remaining)
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	doGet(t, c, "/repos/reposaur/reposaur")
+
+	start := time.Now()
+
+	doGet(t, c, "/repos/reposaur/reposaur")
+
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Fatalf("expected request to wait until the rate limit reset, waited %s", elapsed)
+	}
+
+	// The GraphQL API has a separate budget.
+	start = time.Now()
+
+	doGet(t, c, "/graphql")
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected GraphQL request not to wait, waited %s", elapsed)
+	}
+}
+
+func TestRateLimitRetry(t *testing.T) {
+	tests := map[string]func(w http.ResponseWriter){
+		"primary": func(w http.ResponseWriter) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+		},
+		"secondary": func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+		},
+	}
+
+	for name, limited := range tests {
+		var calls int32
+
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				limited(w)
+				return
+			}
+
+			_, _ = w.Write([]byte(`{}`))
+		})
+
+		resp := doGet(t, c, "/repos/reposaur/reposaur")
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected status 200 got %d", name, resp.StatusCode)
+		}
+
+		if calls != 2 {
+			t.Fatalf("%s: expected 2 requests got %d", name, calls)
+		}
+	}
+}
+
+func TestWriteRequestRetries(t *testing.T) {
+	tests := map[string]struct {
+		first    func(w http.ResponseWriter)
+		expected int32
+	}{
+		// Server errors may happen after the request was applied.
+		"server error": {
+			first: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			expected: 1,
+		},
+		// Rate-limited requests are rejected without being processed.
+		"rate limited": {
+			first: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+			},
+			expected: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		var calls int32
+
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				tt.first(w)
+				return
+			}
+
+			_, _ = w.Write([]byte(`{}`))
+		})
+
+		req, err := c.NewRequest(http.MethodPost, "/repos/reposaur/reposaur/issues", []byte(`{"title":"test"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := c.Do(req.WithContext(context.Background()))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		resp.Body.Close()
+
+		if calls != tt.expected {
+			t.Fatalf("%s: expected %d requests got %d", name, tt.expected, calls)
+		}
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	var calls, notModified int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+
+		const etag = `"v1"`
+
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(`{"name":"reposaur"}`))
+	}))
+	defer srv.Close()
+
+	cache, err := client.NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newClient := func(token string) *client.Client {
+		c := client.NewTokenClient(context.Background(), token)
+		c.BaseURL, _ = url.Parse(srv.URL)
+		c.Cache = cache
+
+		return c
+	}
+
+	get := func(c *client.Client) string {
+		req, err := c.NewRequest(http.MethodGet, "/repos/reposaur/reposaur", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := c.Do(req.WithContext(context.Background()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200 got %d", resp.StatusCode)
+		}
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(b)
+	}
+
+	for i := 0; i < 2; i++ {
+		if body := get(newClient("a")); body != `{"name":"reposaur"}` {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	}
+
+	if notModified != 1 {
+		t.Fatalf("expected 1 revalidated response got %d", notModified)
+	}
+
+	// Responses aren't shared between identities.
+	get(newClient("b"))
+
+	if calls != 3 || notModified != 1 {
+		t.Fatalf("expected 3 requests and 1 revalidated response got %d and %d", calls, notModified)
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+
+		if r.Method == http.MethodPost {
+			b, _ := io.ReadAll(r.Body)
+			_, _ = w.Write(b)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.RequestURI() + `"}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+
+	send := func(c *client.Client, method, url, body string) (string, error) {
+		var rawBody any
+		if body != "" {
+			rawBody = []byte(body)
+		}
+
+		req, err := c.NewRequest(method, url, rawBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := c.Do(req.WithContext(context.Background()))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(b), nil
+	}
+
+	recorder := client.NewTokenClient(context.Background(), "secret")
+	recorder.BaseURL, _ = url.Parse(srv.URL)
+	recorder.Fixtures = client.NewRecorder(path)
+
+	requests := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{method: http.MethodGet, url: "/repos/reposaur/reposaur?per_page=100"},
+		{method: http.MethodPost, url: "/graphql", body: `{"query":"{ viewer { login } }"}`},
+	}
+
+	recorded := make([]string, len(requests))
+
+	for i, r := range requests {
+		var err error
+		if recorded[i], err = send(recorder, r.method, r.url, r.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := recorder.Fixtures.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(b), "secret") {
+		t.Fatal("expected fixtures not to include credentials")
+	}
+
+	fixtures, err := client.NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replayed requests are never sent, so the host doesn't matter.
+	srv.Close()
+
+	replayer := client.NewClient(nil)
+	replayer.BaseURL, _ = url.Parse("http://localhost:1")
+	replayer.Fixtures = fixtures
+
+	for i, r := range requests {
+		body, err := send(replayer, r.method, r.url, r.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Fixtures are indented when saved.
+		compacted := &bytes.Buffer{}
+		if err := json.Compact(compacted, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+
+		if compacted.String() != recorded[i] {
+			t.Fatalf("expected replayed body '%s' got '%s'", recorded[i], body)
+		}
+	}
+
+	if _, err := send(replayer, http.MethodGet, "/repos/reposaur/unknown", ""); err == nil {
+		t.Fatal("expected an error for a request without a fixture")
+	}
+}
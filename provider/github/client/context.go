@@ -0,0 +1,33 @@
+package client
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying c. Built-in functions use
+// the client in the context, if any, instead of the provider's client.
+// Useful to scope requests to a GitHub App installation per evaluation.
+func NewContext(ctx context.Context, c *Client) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the client carried by ctx, if any.
+func FromContext(ctx context.Context) (*Client, bool) {
+	c, ok := ctx.Value(contextKey{}).(*Client)
+	return c, ok && c != nil
+}
+
+type idempotentKey struct{}
+
+// NewIdempotentContext returns a copy of ctx that marks the requests sent
+// with it as safe to retry on any failure, regardless of their method.
+// Useful for requests that only read data, e.g. GraphQL queries.
+func NewIdempotentContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// isIdempotent reports whether ctx marks requests as safe to retry.
+func isIdempotent(ctx context.Context) bool {
+	idempotent, _ := ctx.Value(idempotentKey{}).(bool)
+	return idempotent
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// fixtureHeaders are the response headers kept in fixtures. Other headers
+// are either noise (e.g. Date) or would change the client behavior
+// on replay (e.g. X-RateLimit-Remaining).
+var fixtureHeaders = []string{"Content-Type", "Link"}
+
+// Fixture is a request sent by a Client and its response.
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest is a recorded request. Headers aren't recorded
+// so that credentials never end up in a fixture file.
+type FixtureRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// FixtureResponse is a recorded response.
+type FixtureResponse struct {
+	StatusCode int             `json:"status"`
+	Header     http.Header     `json:"header,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// Fixtures records the requests sent by a Client and their responses
+// into a file, or replays them from it without sending any request.
+//
+// Requests are matched by method, path with query string and body,
+// so fixtures can be recorded against one host and replayed
+// against another.
+type Fixtures struct {
+	path   string
+	replay bool
+
+	mu       sync.Mutex
+	fixtures map[string]Fixture
+}
+
+// NewRecorder returns fixtures that record every request and response
+// until saved to path.
+func NewRecorder(path string) *Fixtures {
+	return &Fixtures{
+		path:     path,
+		fixtures: map[string]Fixture{},
+	}
+}
+
+// NewReplayer returns fixtures that replay the responses recorded in path.
+func NewReplayer(path string) (*Fixtures, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fixtures: %w", err)
+	}
+
+	var fixtures []Fixture
+	if err := json.Unmarshal(b, &fixtures); err != nil {
+		return nil, fmt.Errorf("decode fixtures: %w", err)
+	}
+
+	f := &Fixtures{
+		path:     path,
+		replay:   true,
+		fixtures: make(map[string]Fixture, len(fixtures)),
+	}
+
+	for _, fx := range fixtures {
+		key, err := fixtureKey(fx.Request.Method, fx.Request.URL, fx.Request.Body)
+		if err != nil {
+			return nil, fmt.Errorf("decode fixtures: %s %s: %w", fx.Request.Method, fx.Request.URL, err)
+		}
+
+		f.fixtures[key] = fx
+	}
+
+	return f, nil
+}
+
+// Replaying reports whether f replays responses instead of recording them.
+func (f *Fixtures) Replaying() bool {
+	return f.replay
+}
+
+// Save writes the recorded fixtures to the file they were created with.
+// Does nothing if f is replaying.
+func (f *Fixtures) Save() error {
+	if f.replay {
+		return nil
+	}
+
+	f.mu.Lock()
+
+	keys := make([]string, 0, len(f.fixtures))
+	for k := range f.fixtures {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	fixtures := make([]Fixture, 0, len(keys))
+	for _, k := range keys {
+		fixtures = append(fixtures, f.fixtures[k])
+	}
+
+	f.mu.Unlock()
+
+	b, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode fixtures: %w", err)
+	}
+
+	if err := os.WriteFile(f.path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write fixtures: %w", err)
+	}
+
+	return nil
+}
+
+// do replays the response to req, or sends it using c and records
+// its response.
+func (f *Fixtures) do(c *retryablehttp.Client, req *retryablehttp.Request) (*http.Response, error) {
+	reqBody, err := req.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := fixtureKey(req.Method, req.URL.RequestURI(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.replay {
+		f.mu.Lock()
+		fx, ok := f.fixtures[key]
+		f.mu.Unlock()
+
+		if !ok {
+			return nil, fmt.Errorf("no recorded response for %s %s in %s", req.Method, req.URL.RequestURI(), f.path)
+		}
+
+		return fx.Response.response(req.Request), nil
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	fx := Fixture{
+		Request: FixtureRequest{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Body:   rawJSON(reqBody),
+		},
+		Response: FixtureResponse{
+			StatusCode: resp.StatusCode,
+			Header:     http.Header{},
+			Body:       rawJSON(body),
+		},
+	}
+
+	for _, h := range fixtureHeaders {
+		if v := resp.Header.Values(h); len(v) > 0 {
+			fx.Response.Header[h] = v
+		}
+	}
+
+	f.mu.Lock()
+	f.fixtures[key] = fx
+	f.mu.Unlock()
+
+	return resp, nil
+}
+
+func (fr FixtureResponse) response(req *http.Request) *http.Response {
+	header := fr.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fr.StatusCode, http.StatusText(fr.StatusCode)),
+		StatusCode:    fr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(fr.Body)),
+		ContentLength: int64(len(fr.Body)),
+		Request:       req,
+	}
+}
+
+// fixtureKey returns the key used to match a request with a fixture.
+// JSON bodies are compacted so hand-written fixtures can be indented.
+func fixtureKey(method, url string, body []byte) (string, error) {
+	buf := &bytes.Buffer{}
+
+	if len(body) > 0 {
+		if err := json.Compact(buf, body); err != nil {
+			return "", fmt.Errorf("request body isn't valid JSON: %w", err)
+		}
+	}
+
+	return method + " " + url + " " + buf.String(), nil
+}
+
+// rawJSON returns b as a JSON value. Bodies that aren't JSON
+// are stored as a JSON string.
+func rawJSON(b []byte) json.RawMessage {
+	b = bytes.TrimSpace(b)
+
+	if len(b) == 0 {
+		return nil
+	}
+
+	if json.Valid(b) {
+		return b
+	}
+
+	s, _ := json.Marshal(string(b))
+
+	return s
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+var linkNextRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// NextPageURL returns the URL of the next page advertised by the
+// Link header of resp. Returns an empty string if there's no next page.
+func NextPageURL(resp *http.Response) string {
+	matches := linkNextRegex.FindStringSubmatch(resp.Header.Get("Link"))
+	if len(matches) != 2 {
+		return ""
+	}
+
+	return matches[1]
+}
+
+// GetAll requests path and follows the Link headers of each response,
+// returning the items of every page. Every page must have an array body.
+// If maxPages is greater than zero, at most maxPages pages are requested.
+func (c Client) GetAll(ctx context.Context, path string, maxPages int) ([]any, error) {
+	var (
+		items []any
+		next  = path
+	)
+
+	for page := 1; next != ""; page++ {
+		if maxPages > 0 && page > maxPages {
+			break
+		}
+
+		var pageItems []any
+
+		resp, err := c.GetJSON(ctx, next, &pageItems)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, pageItems...)
+		next = NextPageURL(resp)
+	}
+
+	return items, nil
+}
+
+// GetJSON requests path and decodes the JSON body into v. Returns an
+// error if the response status isn't 200 OK. The returned response body
+// is already closed, it's returned so that headers can be inspected.
+func (c Client) GetJSON(ctx context.Context, path string, v any) (*http.Response, error) {
+	req, err := c.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: unexpected status %d", path, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("get %s: %w", path, err)
+	}
+
+	return resp, nil
+}
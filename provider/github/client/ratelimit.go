@@ -0,0 +1,259 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// CoreResource is the rate-limit resource of most REST API endpoints.
+	CoreResource = "core"
+
+	// GraphQLResource is the rate-limit resource of the GraphQL API.
+	GraphQLResource = "graphql"
+
+	// SearchResource is the rate-limit resource of the search endpoints.
+	SearchResource = "search"
+)
+
+// secondaryRateLimitWait is how long to wait after hitting a secondary
+// rate limit that doesn't include a Retry-After header, as recommended
+// by GitHub.
+const secondaryRateLimitWait = time.Minute
+
+// RateLimit is the rate-limit state of a resource, as reported by
+// the X-RateLimit-* headers of the last response.
+type RateLimit struct {
+	Resource  string
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     time.Time
+}
+
+// rateLimiter keeps track of the rate-limit state of each resource.
+// It's shared between copies of the same Client.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limits map[string]RateLimit
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		limits: map[string]RateLimit{},
+	}
+}
+
+// get returns the rate-limit state of resource, if known.
+func (rl *rateLimiter) get(resource string) (RateLimit, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	l, ok := rl.limits[resource]
+	return l, ok
+}
+
+// all returns the rate-limit state of every known resource.
+func (rl *rateLimiter) all() map[string]RateLimit {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	limits := make(map[string]RateLimit, len(rl.limits))
+	for k, v := range rl.limits {
+		limits[k] = v
+	}
+
+	return limits
+}
+
+// update stores the rate-limit state reported by resp, if any.
+func (rl *rateLimiter) update(resp *http.Response) {
+	l, ok := parseRateLimit(resp)
+	if !ok {
+		return
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.limits[l.Resource] = l
+}
+
+// wait blocks until the budget of the resource used by req is reset,
+// if it was exhausted by a previous request.
+func (rl *rateLimiter) wait(req *retryablehttp.Request) error {
+	l, ok := rl.get(resourceFor(req.URL.Path))
+	if !ok || l.Remaining > 0 {
+		return nil
+	}
+
+	d := time.Until(l.Reset)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// checkRetry updates the rate-limit state on every attempt and retries
+// requests that were rate limited, falling back to the default policy.
+func (rl *rateLimiter) checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	if err == nil && resp != nil {
+		rl.update(resp)
+
+		if isRateLimited(resp) {
+			return true, nil
+		}
+	}
+
+	return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+}
+
+// checkWriteRetry updates the rate-limit state on every attempt and only
+// retries requests that were rate limited, since GitHub rejects them
+// without processing them. Any other failure, including connection
+// errors, is returned as is, since the request may have been applied.
+func (rl *rateLimiter) checkWriteRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	if err == nil && resp != nil {
+		rl.update(resp)
+
+		return isRateLimited(resp), nil
+	}
+
+	return false, nil
+}
+
+// backoff waits for as long as a rate-limited response asks to,
+// falling back to the default exponential backoff.
+func (rl *rateLimiter) backoff(min, max time.Duration, attempt int, resp *http.Response) time.Duration {
+	if resp != nil && isRateLimited(resp) {
+		if d, ok := retryAfter(resp); ok {
+			return d
+		}
+
+		if l, ok := parseRateLimit(resp); ok && l.Remaining == 0 {
+			if d := time.Until(l.Reset); d > 0 {
+				return d
+			}
+
+			return 0
+		}
+
+		return secondaryRateLimitWait
+	}
+
+	return retryablehttp.DefaultBackoff(min, max, attempt, resp)
+}
+
+// isRateLimited reports whether resp was rejected because of either
+// a primary or a secondary rate limit.
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+
+	if _, ok := retryAfter(resp); ok {
+		return true
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+
+	return isSecondaryRateLimit(resp)
+}
+
+// isSecondaryRateLimit reports whether the body of resp mentions a
+// secondary rate limit. The body is restored so it can be read again.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	if resp.Body == nil {
+		return false
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	if err != nil {
+		return false
+	}
+
+	return bytes.Contains(bytes.ToLower(b), []byte("secondary rate limit"))
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	secs, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(secs) * time.Second, true
+}
+
+func parseRateLimit(resp *http.Response) (RateLimit, bool) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	var (
+		limit, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+		used, _  = strconv.Atoi(resp.Header.Get("X-RateLimit-Used"))
+		reset, _ = strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		resource = resp.Header.Get("X-RateLimit-Resource")
+	)
+
+	if resource == "" && resp.Request != nil {
+		resource = resourceFor(resp.Request.URL.Path)
+	}
+
+	return RateLimit{
+		Resource:  resource,
+		Limit:     limit,
+		Remaining: remaining,
+		Used:      used,
+		Reset:     time.Unix(reset, 0),
+	}, true
+}
+
+// resourceFor returns the rate-limit resource used by requests to path.
+func resourceFor(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/graphql"):
+		return GraphQLResource
+
+	case strings.Contains(path, "/search/"):
+		return SearchResource
+	}
+
+	return CoreResource
+}
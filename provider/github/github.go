@@ -11,42 +11,73 @@ const (
 	OrganizationNamespace provider.Namespace = "github.organization"
 	PullRequestNamespace  provider.Namespace = "github.pull_request"
 	RepositoryNamespace   provider.Namespace = "github.repository"
+	TeamNamespace         provider.Namespace = "github.team"
 	UserNamespace         provider.Namespace = "github.user"
 )
 
+// DefaultMaxPages is the default maximum number of pages
+// requested by github.request and github.graphql_paginate.
+const DefaultMaxPages = 10
+
 type GitHub struct {
 	client      *client.Client
 	dataDeriver *DataDeriver
 	builtins    []provider.Builtin
+	maxPages    int
+}
+
+// Option represents a GitHub provider option.
+type Option func(*GitHub)
+
+// WithMaxPages sets the maximum number of pages requested when
+// paginating. If zero or negative, every page is requested.
+func WithMaxPages(n int) Option {
+	return func(gh *GitHub) {
+		gh.maxPages = n
+	}
 }
 
-func NewProvider(c *client.Client) *GitHub {
+func NewProvider(c *client.Client, opts ...Option) *GitHub {
 	if c == nil {
 		c = client.NewClient(nil)
 	}
 
-	return &GitHub{
-		client: c,
-		builtins: []provider.Builtin{
-			&builtin.GraphQL{Client: c},
-			&builtin.Request{Client: c},
-		},
+	gh := &GitHub{
+		client:   c,
+		maxPages: DefaultMaxPages,
 		dataDeriver: &DataDeriver{
 			namespaceToKeys: map[provider.Namespace][]string{
 				IssueNamespace:        {"reactions", "closed_by"},
 				OrganizationNamespace: {"login", "members_url"},
 				PullRequestNamespace:  {"base", "head"},
 				RepositoryNamespace:   {"owner", "full_name"},
+				TeamNamespace:         {"slug", "repositories_url"},
 				UserNamespace:         {"login", "hireable"},
 			},
 		},
 	}
+
+	for _, opt := range opts {
+		opt(gh)
+	}
+
+	gh.builtins = []provider.Builtin{
+		&builtin.GraphQL{Client: c},
+		&builtin.GraphQLPaginate{Client: c, MaxPages: gh.maxPages},
+		&builtin.Request{Client: c, MaxPages: gh.maxPages},
+	}
+
+	return gh
 }
 
 func (gh GitHub) DeriveNamespace(data map[string]any) (provider.Namespace, error) {
 	return gh.dataDeriver.DeriveNamespace(data)
 }
 
+func (gh GitHub) ScoreNamespaces(data map[string]any) []provider.Candidate {
+	return gh.dataDeriver.ScoreNamespaces(data)
+}
+
 func (gh GitHub) DeriveProperties(namespace provider.Namespace, data map[string]any) (map[string]any, error) {
 	return gh.dataDeriver.DeriveProperties(namespace, data)
 }
@@ -60,23 +91,13 @@ type DataDeriver struct {
 }
 
 func (d DataDeriver) DeriveNamespace(data map[string]any) (provider.Namespace, error) {
-	for namespace, keys := range d.namespaceToKeys {
-		var matches int
-
-		for _, key := range keys {
-			for dataKey := range data {
-				if key == dataKey {
-					matches++
-				}
-			}
-
-			if matches == len(keys) {
-				return namespace, nil
-			}
-		}
-	}
+	return provider.BestCandidate(d.ScoreNamespaces(data))
+}
 
-	return "", provider.ErrNonDerivable
+// ScoreNamespaces returns every namespace whose keys are all present in
+// data, scored by the number of keys. See provider.ScoreKeys.
+func (d DataDeriver) ScoreNamespaces(data map[string]any) []provider.Candidate {
+	return provider.ScoreKeys(d.namespaceToKeys, data)
 }
 
 func (d DataDeriver) DeriveProperties(namespace provider.Namespace, data map[string]any) (map[string]any, error) {
@@ -107,6 +128,19 @@ func (d DataDeriver) DeriveProperties(namespace provider.Namespace, data map[str
 
 		return props, nil
 
+	case TeamNamespace:
+		props := map[string]any{}
+
+		if slug, ok := data["slug"]; ok {
+			props["slug"] = slug
+		}
+
+		if name, ok := data["name"]; ok {
+			props["name"] = name
+		}
+
+		return props, nil
+
 	case RepositoryNamespace:
 		props := map[string]any{}
 
@@ -1,10 +1,19 @@
 package github_test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
 
+	"github.com/open-policy-agent/opa/rego"
 	"github.com/reposaur/reposaur/provider"
 	"github.com/reposaur/reposaur/provider/github"
+	"github.com/reposaur/reposaur/provider/github/client"
 )
 
 func TestDeriveNamespace(t *testing.T) {
@@ -27,6 +36,10 @@ func TestDeriveNamespace(t *testing.T) {
 			"owner":     "reposaur",
 			"full_name": "reposaur/reposaur",
 		},
+		github.TeamNamespace: {
+			"slug":             "maintainers",
+			"repositories_url": "https://api.github.com/teams/1/repos",
+		},
 		github.UserNamespace: {
 			"login":    "crqra",
 			"hireable": true,
@@ -44,3 +57,286 @@ func TestDeriveNamespace(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestPagination(t *testing.T) {
+	const lastPage = 3
+
+	var srvURL string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/reposaur/repos" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		if page < lastPage {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/reposaur/repos?page=%d>; rel="next", <%s/orgs/reposaur/repos?page=%d>; rel="last"`, srvURL, page+1, srvURL, lastPage))
+		}
+
+		_ = json.NewEncoder(w).Encode([]map[string]any{{"name": fmt.Sprintf("repo-%d", page)}})
+	}))
+	defer srv.Close()
+
+	srvURL = srv.URL
+
+	c := client.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL)
+
+	tests := []struct {
+		query    string
+		maxPages int
+		expected int
+	}{
+		{query: `github.request("GET /orgs/{org}/repos", {"org": "reposaur"})`, maxPages: github.DefaultMaxPages, expected: 1},
+		{query: `github.request("GET /orgs/{org}/repos", {"org": "reposaur", "_paginate": true})`, maxPages: github.DefaultMaxPages, expected: 3},
+		{query: `github.request("GET /orgs/{org}/repos", {"org": "reposaur", "_paginate": true})`, maxPages: 2, expected: 2},
+		{query: `github.request("GET /orgs/{org}/repos", {"org": "reposaur", "_paginate": true, "_max_pages": 0})`, maxPages: 2, expected: 3},
+		{query: `github.request("GET /orgs/{org}/repos", {"org": "nobody", "_paginate": true})`, maxPages: github.DefaultMaxPages, expected: -1},
+	}
+
+	for _, tt := range tests {
+		opts := []func(*rego.Rego){
+			rego.Query("resp := " + tt.query),
+		}
+
+		for _, b := range github.NewProvider(c, github.WithMaxPages(tt.maxPages)).Builtins() {
+			opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+		}
+
+		rs, err := rego.New(opts...).Eval(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+
+		resp := rs[0].Bindings["resp"].(map[string]any)
+
+		if tt.expected < 0 {
+			if status := resp["status"]; status != json.Number("404") {
+				t.Fatalf("%s: expected status 404 got %v", tt.query, status)
+			}
+
+			continue
+		}
+
+		if status := resp["status"]; status != json.Number("200") {
+			t.Fatalf("%s: expected status 200 got %v", tt.query, status)
+		}
+
+		if repos := resp["body"].([]any); len(repos) != tt.expected {
+			t.Fatalf("%s: expected %d repositories got %d", tt.query, tt.expected, len(repos))
+		}
+	}
+}
+
+func TestGraphQLPaginate(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"data":{"organization":{"team":{"members":{"nodes":[{"login":"a"},{"login":"b"}],"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}}}`,
+		"c1": `{"data":{"organization":{"team":{"members":{"nodes":[{"login":"c"}],"pageInfo":{"hasNextPage":true,"endCursor":"c2"}}}}}}`,
+		"c2": `{"data":{"organization":{"team":{"members":{"nodes":[{"login":"d"}],"pageInfo":{"hasNextPage":false,"endCursor":"c3"}}}}}}`,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Variables["org"] != "reposaur" {
+			t.Errorf("expected variable org to be 'reposaur' got '%v'", body.Variables["org"])
+		}
+
+		cursor, _ := body.Variables["cursor"].(string)
+		_, _ = w.Write([]byte(pages[cursor]))
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL)
+
+	tests := []struct {
+		maxPages int
+		expected []string
+	}{
+		{maxPages: github.DefaultMaxPages, expected: []string{"a", "b", "c", "d"}},
+		{maxPages: 2, expected: []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		opts := []func(*rego.Rego){
+			rego.Query(`resp := github.graphql_paginate("query($org: String!, $cursor: String) { ... }", {"org": "reposaur"}, "organization.team.members")`),
+		}
+
+		for _, b := range github.NewProvider(c, github.WithMaxPages(tt.maxPages)).Builtins() {
+			opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+		}
+
+		rs, err := rego.New(opts...).Eval(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp := rs[0].Bindings["resp"].(map[string]any)
+		data := resp["body"].(map[string]any)["data"].(map[string]any)
+		members := data["organization"].(map[string]any)["team"].(map[string]any)["members"].(map[string]any)
+		nodes := members["nodes"].([]any)
+
+		if len(nodes) != len(tt.expected) {
+			t.Fatalf("expected %d nodes got %d", len(tt.expected), len(nodes))
+		}
+
+		for i, login := range tt.expected {
+			if got := nodes[i].(map[string]any)["login"]; got != login {
+				t.Fatalf("expected node %d to be '%s' got '%v'", i, login, got)
+			}
+		}
+	}
+}
+
+func TestRequestWriteMode(t *testing.T) {
+	var requests []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("expected JSON body: %v", err)
+		}
+
+		requests = append(requests, fmt.Sprintf("%s %s %v", r.Method, r.URL.Path, body["names"]))
+		_, _ = w.Write([]byte(`{"names":["reposaur"]}`))
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL)
+
+	tests := []struct {
+		mode     provider.WriteMode
+		status   json.Number
+		requests int
+		err      bool
+	}{
+		{mode: provider.WriteDenied, err: true},
+		{mode: provider.WriteDryRun, status: "0"},
+		{mode: provider.WriteAllowed, status: "200", requests: 1},
+	}
+
+	for _, tt := range tests {
+		requests = nil
+
+		opts := []func(*rego.Rego){
+			rego.Query(`resp := github.request("PUT /repos/{owner}/{repo}/topics", {"owner": "reposaur", "repo": "reposaur", "names": ["reposaur"]})`),
+			rego.StrictBuiltinErrors(true),
+		}
+
+		for _, b := range github.NewProvider(c).Builtins() {
+			opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+		}
+
+		ctx := provider.NewWriteModeContext(context.Background(), tt.mode)
+
+		rs, err := rego.New(opts...).Eval(ctx)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("mode %d: expected an error", tt.mode)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("mode %d: %v", tt.mode, err)
+		}
+
+		resp := rs[0].Bindings["resp"].(map[string]any)
+
+		if status := resp["status"]; status != tt.status {
+			t.Fatalf("mode %d: expected status %s got %v", tt.mode, tt.status, status)
+		}
+
+		if len(requests) != tt.requests {
+			t.Fatalf("mode %d: expected %d requests got %d", tt.mode, tt.requests, len(requests))
+		}
+
+		if tt.requests > 0 && requests[0] != "PUT /repos/reposaur/reposaur/topics [reposaur]" {
+			t.Fatalf("mode %d: unexpected request '%s'", tt.mode, requests[0])
+		}
+	}
+}
+
+func TestGraphQLWriteMode(t *testing.T) {
+	var requests int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL)
+
+	var (
+		query    = `query { viewer { login } }`
+		mutation = `mutation($id: ID!) { addStar(input: {starrableId: $id}) { clientMutationId } }`
+	)
+
+	tests := []struct {
+		query    string
+		mode     provider.WriteMode
+		status   json.Number
+		requests int
+		err      bool
+	}{
+		{query: query, mode: provider.WriteDenied, status: "200", requests: 1},
+		{query: mutation, mode: provider.WriteDenied, err: true},
+		{query: mutation, mode: provider.WriteDryRun, status: "0"},
+		{query: mutation, mode: provider.WriteAllowed, status: "200", requests: 1},
+	}
+
+	for i, tt := range tests {
+		requests = 0
+
+		opts := []func(*rego.Rego){
+			rego.Query(fmt.Sprintf(`resp := github.graphql(%q, {"id": "1"})`, tt.query)),
+			rego.StrictBuiltinErrors(true),
+		}
+
+		for _, b := range github.NewProvider(c).Builtins() {
+			opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+		}
+
+		ctx := provider.NewWriteModeContext(context.Background(), tt.mode)
+
+		rs, err := rego.New(opts...).Eval(ctx)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("%d: expected an error", i)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+
+		resp := rs[0].Bindings["resp"].(map[string]any)
+
+		if status := resp["status"]; status != tt.status {
+			t.Fatalf("%d: expected status %s got %v", i, tt.status, status)
+		}
+
+		if requests != tt.requests {
+			t.Fatalf("%d: expected %d requests got %d", i, tt.requests, requests)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package builtin
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,9 +11,14 @@ import (
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/types"
+	"github.com/reposaur/reposaur/provider"
 	"github.com/reposaur/reposaur/provider/github/client"
 )
 
+// graphqlMockKey is the key of the mocked GraphQL responses,
+// see provider.Mocks.
+const graphqlMockKey = "POST /graphql"
+
 type GraphQL struct {
 	Client *client.Client
 }
@@ -31,30 +37,33 @@ func (gql GraphQL) Func() *rego.Function {
 	}
 }
 
-func (gql GraphQL) Impl(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
-	req, err := gql.argsToRequest(terms)
+func (gql GraphQL) Impl(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+	if c, ok := client.FromContext(bctx.Context); ok {
+		gql.Client = c
+	}
+
+	query, vars, err := gql.parseArgs(terms)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := gql.Client.Do(req)
+	ctx, allowed, err := checkMutation(bctx.Context, gql.Client, query, vars)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var finalResp response
+	if !allowed {
+		return ast.NewTerm(ast.MustInterfaceToValue(response{})), nil
+	}
 
-	dec := json.NewDecoder(resp.Body)
-	if err := dec.Decode(&finalResp.Body); err != nil {
+	req, err := newGraphQLRequest(gql.Client, query, vars)
+	if err != nil {
 		return nil, err
 	}
 
-	finalResp.StatusCode = resp.StatusCode
-
-	if finalResp.StatusCode == http.StatusForbidden {
-		b := finalResp.Body.(map[string]interface{})
-		return nil, fmt.Errorf("forbidden: %s", b["message"])
+	finalResp, _, err := send(ctx, gql.Client, req, graphqlMockKey)
+	if err != nil {
+		return nil, err
 	}
 
 	val, err := ast.InterfaceToValue(finalResp)
@@ -65,10 +74,10 @@ func (gql GraphQL) Impl(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, er
 	return ast.NewTerm(val), nil
 }
 
-func (gql GraphQL) argsToRequest(terms []*ast.Term) (*retryablehttp.Request, error) {
+func (gql GraphQL) parseArgs(terms []*ast.Term) (string, map[string]any, error) {
 	// FIXME: Function receives 2 arguments but terms includes one additional at last index
 	if len(terms) != 3 {
-		return nil, fmt.Errorf("wrong number of arguments, expected 2 got %d", len(terms)-1)
+		return "", nil, fmt.Errorf("wrong number of arguments, expected 2 got %d", len(terms)-1)
 	}
 
 	var (
@@ -77,13 +86,45 @@ func (gql GraphQL) argsToRequest(terms []*ast.Term) (*retryablehttp.Request, err
 	)
 
 	if err := ast.As(terms[0].Value, &query); err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
 	if err := ast.As(terms[1].Value, &vars); err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
+	return query, vars, nil
+}
+
+// checkMutation applies the write mode in ctx to query if it's a
+// mutation, unless it's mocked and so never sent. Returns false if the
+// mutation must not be sent. Queries only read data, so the returned
+// context marks them as safe to retry.
+func checkMutation(ctx context.Context, c *client.Client, query string, vars map[string]any) (context.Context, bool, error) {
+	if !provider.IsGraphQLMutation(query) {
+		return client.NewIdempotentContext(ctx), true, nil
+	}
+
+	if _, mocked := provider.MocksFromContext(ctx); mocked {
+		return ctx, true, nil
+	}
+
+	body, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return ctx, false, err
+	}
+
+	u, err := c.BaseURL.Parse("/graphql")
+	if err != nil {
+		return ctx, false, err
+	}
+
+	allowed, err := provider.CheckWrite(ctx, http.MethodPost, u.String(), body)
+
+	return ctx, allowed, err
+}
+
+func newGraphQLRequest(c *client.Client, query string, vars map[string]any) (*retryablehttp.Request, error) {
 	body := map[string]any{
 		"query":     query,
 		"variables": vars,
@@ -96,5 +137,5 @@ func (gql GraphQL) argsToRequest(terms []*ast.Term) (*retryablehttp.Request, err
 		return nil, err
 	}
 
-	return gql.Client.NewRequest(http.MethodPost, "/graphql", buf)
+	return c.NewRequest(http.MethodPost, "/graphql", buf)
 }
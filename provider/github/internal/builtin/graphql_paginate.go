@@ -0,0 +1,181 @@
+package builtin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/github/client"
+)
+
+// cursorVar is the name of the query variable that receives the
+// cursor of the next page.
+const cursorVar = "cursor"
+
+// GraphQLPaginate runs a GraphQL query once per page of the connection
+// at a given path, merging its nodes and edges into a single response.
+type GraphQLPaginate struct {
+	Client *client.Client
+
+	// MaxPages is the maximum number of pages requested. If zero or
+	// negative, every page is requested.
+	MaxPages int
+}
+
+func (gql GraphQLPaginate) Func() *rego.Function {
+	return &rego.Function{
+		Name: "github.graphql_paginate",
+		Decl: types.NewFunction(
+			types.Args(
+				types.S,
+				types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)),
+				types.S,
+			),
+			types.A,
+		),
+		Memoize: true,
+	}
+}
+
+func (gql GraphQLPaginate) Impl(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+	if c, ok := client.FromContext(bctx.Context); ok {
+		gql.Client = c
+	}
+
+	// FIXME: Function receives 3 arguments but terms includes one additional at last index
+	if len(terms) != 4 {
+		return nil, fmt.Errorf("wrong number of arguments, expected 3 got %d", len(terms)-1)
+	}
+
+	var (
+		query string
+		vars  map[string]any
+		path  string
+	)
+
+	if err := ast.As(terms[0].Value, &query); err != nil {
+		return nil, err
+	}
+
+	if err := ast.As(terms[1].Value, &vars); err != nil {
+		return nil, err
+	}
+
+	if err := ast.As(terms[2].Value, &path); err != nil {
+		return nil, err
+	}
+
+	if vars == nil {
+		vars = map[string]any{}
+	}
+
+	if provider.IsGraphQLMutation(query) {
+		return nil, fmt.Errorf("graphql_paginate: mutations can't be paginated")
+	}
+
+	// Queries only read data, so they're safe to retry.
+	ctx := client.NewIdempotentContext(bctx.Context)
+
+	var (
+		finalResp *response
+		conn      map[string]any
+		nodes     []any
+		edges     []any
+	)
+
+	for page := 1; gql.MaxPages <= 0 || page <= gql.MaxPages; page++ {
+		req, err := newGraphQLRequest(gql.Client, query, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		pageResp, _, err := send(ctx, gql.Client, req, graphqlMockKey)
+		if err != nil {
+			return nil, err
+		}
+
+		pageConn, ok := connectionAt(pageResp.Body, path)
+
+		// Responses without a connection at path (e.g. errors) are
+		// returned as is if they're the first page.
+		if pageResp.StatusCode != http.StatusOK || !ok {
+			if page == 1 {
+				finalResp = pageResp
+				break
+			}
+
+			return nil, fmt.Errorf("graphql_paginate: page %d: no connection at '%s' in response with status %d", page, path, pageResp.StatusCode)
+		}
+
+		if page == 1 {
+			finalResp, conn = pageResp, pageConn
+		}
+
+		if v, ok := pageConn["nodes"].([]any); ok {
+			nodes = append(nodes, v...)
+		}
+
+		if v, ok := pageConn["edges"].([]any); ok {
+			edges = append(edges, v...)
+		}
+
+		conn["pageInfo"] = pageConn["pageInfo"]
+
+		pageInfo, _ := pageConn["pageInfo"].(map[string]any)
+		hasNextPage, _ := pageInfo["hasNextPage"].(bool)
+		endCursor, _ := pageInfo["endCursor"].(string)
+
+		if !hasNextPage || endCursor == "" || endCursor == vars[cursorVar] {
+			break
+		}
+
+		vars[cursorVar] = endCursor
+	}
+
+	if conn != nil {
+		if _, ok := conn["nodes"]; ok {
+			conn["nodes"] = nodes
+		}
+
+		if _, ok := conn["edges"]; ok {
+			conn["edges"] = edges
+		}
+	}
+
+	val, err := ast.InterfaceToValue(finalResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewTerm(val), nil
+}
+
+// connectionAt returns the connection object at the dot-separated path,
+// relative to the "data" field of body.
+func connectionAt(body any, path string) (map[string]any, bool) {
+	obj, ok := body.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	cur, ok := obj["data"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+
+		if cur, ok = cur[key].(map[string]any); !ok {
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
@@ -4,20 +4,31 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
-	"regexp"
 	"strconv"
-	"strings"
 
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/types"
+	"github.com/reposaur/reposaur/provider"
 	"github.com/reposaur/reposaur/provider/github/client"
 )
 
+const (
+	// paginateParam is a reserved parameter that, if true, makes the request
+	// follow the Link headers of each response, concatenating array bodies.
+	paginateParam = "_paginate"
+
+	// maxPagesParam is a reserved parameter that overrides Request.MaxPages.
+	maxPagesParam = "_max_pages"
+)
+
 type Request struct {
 	Client *client.Client
+
+	// MaxPages is the maximum number of pages requested when paginating.
+	// If zero or negative, every page is requested.
+	MaxPages int
 }
 
 func (r Request) Func() *rego.Function {
@@ -34,30 +45,65 @@ func (r Request) Func() *rego.Function {
 	}
 }
 
-func (r Request) Impl(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
-	req, err := r.argsToRequest(terms)
-	if err != nil {
-		return nil, err
+func (r Request) Impl(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+	if c, ok := client.FromContext(bctx.Context); ok {
+		r.Client = c
 	}
 
-	resp, err := r.Client.Do(req)
+	req, opts, err := r.argsToRequest(terms)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var finalResp response
+	// Requests that modify data are subject to the write mode, unless
+	// they're mocked and so never sent.
+	if _, mocked := provider.MocksFromContext(bctx.Context); req.Method != http.MethodGet && !mocked {
+		allowed, err := provider.CheckWrite(bctx.Context, req.Method, req.URL.String(), opts.body)
+		if err != nil {
+			return nil, err
+		}
+
+		if !allowed {
+			return ast.NewTerm(ast.MustInterfaceToValue(response{})), nil
+		}
+	}
 
-	dec := json.NewDecoder(resp.Body)
-	if err := dec.Decode(&finalResp.Body); err != nil {
+	finalResp, next, err := send(bctx.Context, r.Client, req, opts.mockKeys...)
+	if err != nil {
 		return nil, err
 	}
 
-	finalResp.StatusCode = resp.StatusCode
+	// Pagination only makes sense for successful array bodies, any
+	// other response is returned as is.
+	items, isArray := finalResp.Body.([]interface{})
+
+	if opts.paginate && isArray && req.Method == http.MethodGet && finalResp.StatusCode == http.StatusOK {
+		for page := 2; next != ""; page++ {
+			if opts.maxPages > 0 && page > opts.maxPages {
+				break
+			}
 
-	if finalResp.StatusCode == http.StatusForbidden {
-		b := finalResp.Body.(map[string]interface{})
-		return nil, fmt.Errorf("forbidden: %s", b["message"])
+			req, err := r.Client.NewRequest(http.MethodGet, next, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			var pageResp *response
+
+			pageResp, next, err = send(bctx.Context, r.Client, req, opts.mockKeys...)
+			if err != nil {
+				return nil, err
+			}
+
+			pageItems, ok := pageResp.Body.([]interface{})
+			if pageResp.StatusCode != http.StatusOK || !ok {
+				return nil, fmt.Errorf("paginate: page %d: unexpected response with status %d", page, pageResp.StatusCode)
+			}
+
+			items = append(items, pageItems...)
+		}
+
+		finalResp.Body = items
 	}
 
 	val, err := ast.InterfaceToValue(finalResp)
@@ -68,10 +114,24 @@ func (r Request) Impl(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, erro
 	return ast.NewTerm(val), nil
 }
 
-func (r Request) argsToRequest(terms []*ast.Term) (*retryablehttp.Request, error) {
+type requestOptions struct {
+	paginate bool
+	maxPages int
+
+	// body is the JSON body of requests that modify data.
+	body []byte
+
+	// mockKeys are the keys of the mocks matching the request,
+	// see provider.Mocks.
+	mockKeys []string
+}
+
+func (r Request) argsToRequest(terms []*ast.Term) (*retryablehttp.Request, requestOptions, error) {
+	opts := requestOptions{maxPages: r.MaxPages}
+
 	// FIXME: Function receives 2 arguments but terms includes one additional at last index
 	if len(terms) != 3 {
-		return nil, fmt.Errorf("wrong number of arguments, expected 2 got %d", len(terms)-1)
+		return nil, opts, fmt.Errorf("wrong number of arguments, expected 2 got %d", len(terms)-1)
 	}
 
 	var (
@@ -80,96 +140,61 @@ func (r Request) argsToRequest(terms []*ast.Term) (*retryablehttp.Request, error
 	)
 
 	if err := ast.As(terms[0].Value, &path); err != nil {
-		return nil, err
+		return nil, opts, err
 	}
 
 	if err := ast.As(terms[1].Value, &data); err != nil {
-		return nil, err
+		return nil, opts, err
 	}
 
-	method, path, err := r.parsePath(path)
-	if err != nil {
-		return nil, err
-	}
+	if paginate, ok := data[paginateParam]; ok {
+		if opts.paginate, ok = paginate.(bool); !ok {
+			return nil, opts, fmt.Errorf("parse error: %s must be a boolean", paginateParam)
+		}
 
-	if method != http.MethodGet {
-		return nil, fmt.Errorf("only GET requests are supported, got '%s'", method)
+		delete(data, paginateParam)
 	}
 
-	pathParams := r.parsePathParams(path)
-
-	for _, p := range pathParams {
-		v, err := r.valueToString(data[p])
+	if maxPages, ok := data[maxPagesParam]; ok {
+		v, err := provider.ValueToString(maxPages)
 		if err != nil {
-			return nil, err
+			return nil, opts, err
 		}
 
-		path = strings.Replace(path, "{"+p+"}", v, 1)
-		delete(data, p)
-	}
-
-	qs := url.Values{}
-
-	for k, v := range data {
-		v, err := r.valueToString(v)
-		if err != nil {
-			return nil, err
+		if opts.maxPages, err = strconv.Atoi(v); err != nil {
+			return nil, opts, fmt.Errorf("parse error: %s must be an integer", maxPagesParam)
 		}
 
-		qs.Add(k, v)
-		delete(data, k)
+		delete(data, maxPagesParam)
 	}
 
-	u, err := url.Parse(path)
+	pr, err := provider.ParseRequest(path, data)
 	if err != nil {
-		return nil, err
+		return nil, opts, err
 	}
 
-	u.RawQuery = qs.Encode()
-
-	return r.Client.NewRequest(method, u.String(), nil)
-}
-
-func (r Request) parsePath(p string) (string, string, error) {
-	pathParts := strings.Split(p, " ")
+	u := pr.Path
 
-	if len(pathParts) != 2 {
-		return "", "", fmt.Errorf("wrong number of parts in path, expected 2 got %d", len(pathParts))
+	// The remaining parameters are sent in the query string of GET
+	// requests and as the JSON body of any other.
+	if pr.Method != http.MethodGet {
+		if len(pr.Params) > 0 {
+			if opts.body, err = json.Marshal(pr.Params); err != nil {
+				return nil, opts, err
+			}
+		}
+	} else if u, err = pr.URL(); err != nil {
+		return nil, opts, err
 	}
 
-	var (
-		method = strings.ToUpper(pathParts[0])
-		path   = pathParts[1]
-	)
-
-	return method, path, nil
-}
+	opts.mockKeys = pr.MockKeys()
 
-func (r Request) parsePathParams(path string) []string {
-	regex := regexp.MustCompile(`{[a-z]+}`)
-	matches := regex.FindAllString(path, -1)
-
-	var params []string
-	for _, v := range matches {
-		p := strings.Replace(v, "{", "", 1)
-		p = strings.Replace(p, "}", "", 1)
-		params = append(params, p)
+	var body any
+	if opts.body != nil {
+		body = opts.body
 	}
 
-	return params
-}
-
-func (r Request) valueToString(v interface{}) (string, error) {
-	switch tv := v.(type) {
-	case string:
-		return tv, nil
-
-	case json.Number:
-		return tv.String(), nil
-
-	case int64:
-		return strconv.Itoa(int(tv)), nil
-	}
+	req, err := r.Client.NewRequest(pr.Method, u, body)
 
-	return "", fmt.Errorf("parse error: can't parse '%v' to string", v)
+	return req, opts, err
 }
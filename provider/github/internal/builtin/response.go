@@ -1,6 +1,44 @@
 package builtin
 
+import (
+	"context"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/github/client"
+)
+
 type response struct {
 	StatusCode int         `json:"status"`
 	Body       interface{} `json:"body"`
 }
+
+// send sends req using c and decodes its response. Returns the URL
+// of the next page, if any.
+//
+// If ctx carries mocks, req isn't sent and the response is the first
+// mock matching mockKeys instead.
+func send(ctx context.Context, c *client.Client, req *retryablehttp.Request, mockKeys ...string) (*response, string, error) {
+	if mock, mocked, err := provider.MockedResponse(ctx, mockKeys...); mocked {
+		if err != nil {
+			return nil, "", err
+		}
+
+		return &response{StatusCode: mock.StatusCode, Body: mock.Body}, "", nil
+	}
+
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := provider.DecodeBody(resp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	finalResp := &response{StatusCode: resp.StatusCode, Body: body}
+
+	return finalResp, client.NextPageURL(resp), nil
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"golang.org/x/oauth2"
+)
+
+const (
+	DefaultBaseURL = "https://gitlab.com/api/v4"
+)
+
+type Client struct {
+	BaseURL *url.URL
+
+	client     *retryablehttp.Client
+	onceClient *retryablehttp.Client
+	token      string
+}
+
+func NewClient(httpClient *http.Client) *Client {
+	baseURL, _ := url.Parse(DefaultBaseURL)
+	client := newRetryableClient(httpClient)
+
+	onceClient := newRetryableClient(client.HTTPClient)
+	onceClient.CheckRetry = func(ctx context.Context, _ *http.Response, _ error) (bool, error) {
+		return false, ctx.Err()
+	}
+
+	return &Client{
+		BaseURL:    baseURL,
+		client:     client,
+		onceClient: onceClient,
+	}
+}
+
+func NewTokenClient(ctx context.Context, token string) *Client {
+	tokenSrc := oauth2.StaticTokenSource(&oauth2.Token{
+		AccessToken: token,
+	})
+
+	oauthClient := oauth2.NewClient(ctx, tokenSrc)
+
+	client := NewClient(oauthClient)
+	client.token = token
+
+	return client
+}
+
+func (c Client) Client() *http.Client {
+	return c.client.HTTPClient
+}
+
+func (c Client) Token(_ context.Context) (string, error) {
+	return c.token, nil
+}
+
+// GraphQLURL returns the URL of the GraphQL endpoint. GitLab serves it
+// from /api/graphql, outside of the versioned REST API path.
+func (c Client) GraphQLURL() *url.URL {
+	u := *c.BaseURL
+
+	// Escaped segments of the base path are kept as they are.
+	u.RawPath = strings.TrimSuffix(strings.TrimSuffix(u.EscapedPath(), "/"), "/api/v4") + "/api/graphql"
+	u.Path, _ = url.PathUnescape(u.RawPath)
+
+	return &u
+}
+
+// NewRequest creates a new request relative to the REST API base URL.
+// Unlike GitHub, GitLab's REST API lives under a path (e.g. /api/v4), so
+// path is appended to it instead of resolved against it.
+func (c Client) NewRequest(method, path string, rawBody any) (*retryablehttp.Request, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.BaseURL.String(), "/") + "/" + strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.newRequest(method, u, rawBody)
+}
+
+// NewGraphQLRequest creates a new request to the GraphQL endpoint.
+func (c Client) NewGraphQLRequest(rawBody any) (*retryablehttp.Request, error) {
+	return c.newRequest(http.MethodPost, c.GraphQLURL(), rawBody)
+}
+
+func (c Client) Do(req *retryablehttp.Request) (*http.Response, error) {
+	return c.client.Do(req)
+}
+
+// DoOnce sends req without retrying it, for requests that modify
+// data and could be applied more than once if retried.
+func (c Client) DoOnce(req *retryablehttp.Request) (*http.Response, error) {
+	return c.onceClient.Do(req)
+}
+
+func (c Client) newRequest(method string, u *url.URL, rawBody any) (*retryablehttp.Request, error) {
+	req, err := retryablehttp.NewRequest(method, u.String(), rawBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if rawBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	req.Header.Set("User-Agent", "reposaur")
+
+	return req, nil
+}
+
+func newRetryableClient(httpClient *http.Client) *retryablehttp.Client {
+	client := retryablehttp.NewClient()
+
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	}
+
+	return client
+}
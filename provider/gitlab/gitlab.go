@@ -0,0 +1,150 @@
+package gitlab
+
+import (
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/gitlab/client"
+	"github.com/reposaur/reposaur/provider/gitlab/internal/builtin"
+)
+
+const (
+	GroupNamespace        provider.Namespace = "gitlab.group"
+	IssueNamespace        provider.Namespace = "gitlab.issue"
+	MergeRequestNamespace provider.Namespace = "gitlab.merge_request"
+	ProjectNamespace      provider.Namespace = "gitlab.project"
+	UserNamespace         provider.Namespace = "gitlab.user"
+)
+
+type GitLab struct {
+	client      *client.Client
+	dataDeriver *DataDeriver
+	builtins    []provider.Builtin
+}
+
+func NewProvider(c *client.Client) *GitLab {
+	if c == nil {
+		c = client.NewClient(nil)
+	}
+
+	return &GitLab{
+		client: c,
+		builtins: []provider.Builtin{
+			&builtin.GraphQL{Client: c},
+			&builtin.Request{Client: c},
+		},
+		dataDeriver: &DataDeriver{
+			namespaceToKeys: map[provider.Namespace][]string{
+				GroupNamespace:        {"full_path", "parent_id"},
+				IssueNamespace:        {"iid", "issue_type"},
+				MergeRequestNamespace: {"source_branch", "target_branch"},
+				ProjectNamespace:      {"path_with_namespace", "namespace"},
+				UserNamespace:         {"username", "state"},
+			},
+		},
+	}
+}
+
+func (gl GitLab) DeriveNamespace(data map[string]any) (provider.Namespace, error) {
+	return gl.dataDeriver.DeriveNamespace(data)
+}
+
+func (gl GitLab) ScoreNamespaces(data map[string]any) []provider.Candidate {
+	return gl.dataDeriver.ScoreNamespaces(data)
+}
+
+func (gl GitLab) DeriveProperties(namespace provider.Namespace, data map[string]any) (map[string]any, error) {
+	return gl.dataDeriver.DeriveProperties(namespace, data)
+}
+
+func (gl GitLab) Builtins() []provider.Builtin {
+	return gl.builtins
+}
+
+type DataDeriver struct {
+	namespaceToKeys map[provider.Namespace][]string
+}
+
+func (d DataDeriver) DeriveNamespace(data map[string]any) (provider.Namespace, error) {
+	return provider.BestCandidate(d.ScoreNamespaces(data))
+}
+
+// ScoreNamespaces returns every namespace whose keys are all present in
+// data, scored by the number of keys. See provider.ScoreKeys.
+func (d DataDeriver) ScoreNamespaces(data map[string]any) []provider.Candidate {
+	return provider.ScoreKeys(d.namespaceToKeys, data)
+}
+
+func (d DataDeriver) DeriveProperties(namespace provider.Namespace, data map[string]any) (map[string]any, error) {
+	switch namespace {
+	case IssueNamespace, MergeRequestNamespace:
+		props := map[string]any{}
+
+		if id, ok := data["id"]; ok {
+			props["id"] = id
+		}
+
+		if iid, ok := data["iid"]; ok {
+			props["iid"] = iid
+		}
+
+		if projectID, ok := data["project_id"]; ok {
+			props["project_id"] = projectID
+		}
+
+		return props, nil
+
+	case GroupNamespace:
+		props := map[string]any{}
+
+		if id, ok := data["id"]; ok {
+			props["id"] = id
+		}
+
+		if fullPath, ok := data["full_path"]; ok {
+			props["full_path"] = fullPath
+		}
+
+		if name, ok := data["name"]; ok {
+			props["name"] = name
+		}
+
+		return props, nil
+
+	case ProjectNamespace:
+		props := map[string]any{}
+
+		if id, ok := data["id"]; ok {
+			props["id"] = id
+		}
+
+		if namespace, ok := data["namespace"].(map[string]any); ok {
+			if fullPath, ok := namespace["full_path"]; ok {
+				props["namespace"] = fullPath
+			}
+		}
+
+		if path, ok := data["path"]; ok {
+			props["project"] = path
+		}
+
+		if defaultBranch, ok := data["default_branch"]; ok {
+			props["default_branch"] = defaultBranch
+		}
+
+		return props, nil
+
+	case UserNamespace:
+		props := map[string]any{}
+
+		if username, ok := data["username"]; ok {
+			props["username"] = username
+		}
+
+		if name, ok := data["name"]; ok {
+			props["name"] = name
+		}
+
+		return props, nil
+	}
+
+	return nil, provider.ErrNonDerivable
+}
@@ -0,0 +1,303 @@
+package gitlab_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/gitlab"
+	"github.com/reposaur/reposaur/provider/gitlab/client"
+)
+
+func TestDeriveNamespace(t *testing.T) {
+	gl := gitlab.NewProvider(nil)
+
+	testData := map[provider.Namespace]map[string]any{
+		gitlab.GroupNamespace: {
+			"full_path": "reposaur/policies",
+			"parent_id": 1,
+		},
+		gitlab.IssueNamespace: {
+			"iid":        1,
+			"issue_type": "issue",
+		},
+		gitlab.MergeRequestNamespace: {
+			"source_branch": "feat",
+			"target_branch": "main",
+		},
+		gitlab.ProjectNamespace: {
+			"path_with_namespace": "reposaur/reposaur",
+			"namespace":           map[string]any{"full_path": "reposaur"},
+		},
+		gitlab.UserNamespace: {
+			"username": "crqra",
+			"state":    "active",
+		},
+	}
+
+	for expected, data := range testData {
+		namespace, err := gl.DeriveNamespace(data)
+		if err != nil {
+			t.Fatalf("testing %s: %s", expected, err)
+		}
+
+		if namespace != expected {
+			t.Fatalf("expected namespace to be '%s' got '%s'", expected, namespace)
+		}
+	}
+}
+
+func TestDeriveProperties(t *testing.T) {
+	gl := gitlab.NewProvider(nil)
+
+	props, err := gl.DeriveProperties(gitlab.ProjectNamespace, map[string]any{
+		"id":             10,
+		"path":           "reposaur",
+		"default_branch": "main",
+		"namespace":      map[string]any{"full_path": "reposaur"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if props["namespace"] != "reposaur" || props["project"] != "reposaur" || props["default_branch"] != "main" {
+		t.Fatalf("unexpected properties: %v", props)
+	}
+}
+
+func TestRequestBuiltin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Project paths are sent as a single, URL-encoded segment.
+		if r.URL.EscapedPath() != "/gitlab/api/v4/projects/reposaur%2Freposaur/repository/branches" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Project Not Found"}`))
+			return
+		}
+
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("expected Authorization header 'Bearer secret' got '%s'", got)
+		}
+
+		if got := r.URL.Query().Get("per_page"); got != "50" {
+			t.Errorf("expected per_page query parameter '50' got '%s'", got)
+		}
+
+		_ = json.NewEncoder(w).Encode([]map[string]any{{"name": "main"}})
+	}))
+	defer srv.Close()
+
+	c := client.NewTokenClient(context.Background(), "secret")
+	c.BaseURL, _ = url.Parse(srv.URL + "/gitlab/api/v4/")
+
+	opts := []func(*rego.Rego){
+		rego.Query(`resp := gitlab.request("GET /projects/{id}/repository/branches", {"id": "reposaur/reposaur", "per_page": 50})`),
+	}
+
+	for _, b := range gitlab.NewProvider(c).Builtins() {
+		opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+	}
+
+	rs, err := rego.New(opts...).Eval(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := rs[0].Bindings["resp"].(map[string]any)
+
+	if status := resp["status"]; status != json.Number("200") {
+		t.Fatalf("expected status 200 got %v", status)
+	}
+
+	branches := resp["body"].([]any)
+	if len(branches) != 1 || branches[0].(map[string]any)["name"] != "main" {
+		t.Fatalf("unexpected body: %v", resp["body"])
+	}
+}
+
+func TestGraphQLBuiltin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/gitlab/api/graphql" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Not Found"}`))
+			return
+		}
+
+		var body struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+
+		if body.Variables["path"] != "reposaur/reposaur" {
+			t.Errorf("expected path variable 'reposaur/reposaur' got %v", body.Variables["path"])
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{"project": map[string]any{"name": "reposaur"}},
+		})
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL + "/gitlab/api/v4")
+
+	opts := []func(*rego.Rego){
+		rego.Query(`resp := gitlab.graphql("query($path: ID!) { project(fullPath: $path) { name } }", {"path": "reposaur/reposaur"})`),
+	}
+
+	for _, b := range gitlab.NewProvider(c).Builtins() {
+		opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+	}
+
+	rs, err := rego.New(opts...).Eval(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := rs[0].Bindings["resp"].(map[string]any)
+
+	if status := resp["status"]; status != json.Number("200") {
+		t.Fatalf("expected status 200 got %v", status)
+	}
+
+	project := resp["body"].(map[string]any)["data"].(map[string]any)["project"].(map[string]any)
+	if project["name"] != "reposaur" {
+		t.Fatalf("unexpected body: %v", resp["body"])
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	tests := map[string]string{
+		"https://gitlab.com/api/v4":             "https://gitlab.com/api/graphql",
+		"https://gitlab.com/api/v4/":            "https://gitlab.com/api/graphql",
+		"https://example.com/gitlab/api/v4":     "https://example.com/gitlab/api/graphql",
+		"https://example.com/gitlab%2Fx/api/v4": "https://example.com/gitlab%2Fx/api/graphql",
+	}
+
+	for baseURL, expected := range tests {
+		c := client.NewClient(nil)
+		c.BaseURL, _ = url.Parse(baseURL)
+
+		if got := c.GraphQLURL().String(); got != expected {
+			t.Errorf("%s: expected %s got %s", baseURL, expected, got)
+		}
+	}
+}
+
+func TestGraphQLWriteMode(t *testing.T) {
+	var requests int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		// Mutations aren't retried on server errors.
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL + "/api/v4")
+
+	mutation := `mutation { awardEmojiAdd(input: {awardableId: "1", name: "thumbsup"}) { errors } }`
+
+	tests := []struct {
+		mode     provider.WriteMode
+		status   json.Number
+		requests int
+		err      bool
+	}{
+		{mode: provider.WriteDenied, err: true},
+		{mode: provider.WriteDryRun, status: "0"},
+		{mode: provider.WriteAllowed, status: "502", requests: 1},
+	}
+
+	for _, tt := range tests {
+		requests = 0
+
+		opts := []func(*rego.Rego){
+			rego.Query(fmt.Sprintf(`resp := gitlab.graphql(%q, {})`, mutation)),
+			rego.StrictBuiltinErrors(true),
+		}
+
+		for _, b := range gitlab.NewProvider(c).Builtins() {
+			opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+		}
+
+		ctx := provider.NewWriteModeContext(context.Background(), tt.mode)
+
+		rs, err := rego.New(opts...).Eval(ctx)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("mode %d: expected an error", tt.mode)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("mode %d: %v", tt.mode, err)
+		}
+
+		resp := rs[0].Bindings["resp"].(map[string]any)
+
+		if status := resp["status"]; status != tt.status {
+			t.Fatalf("mode %d: expected status %s got %v", tt.mode, tt.status, status)
+		}
+
+		if requests != tt.requests {
+			t.Fatalf("mode %d: expected %d requests got %d", tt.mode, tt.requests, requests)
+		}
+	}
+}
+
+func TestMocks(t *testing.T) {
+	mocks := provider.Mocks{
+		"GET /projects/reposaur/reposaur/repository/branches": map[string]any{"body": []any{map[string]any{"name": "main"}}},
+		"POST /graphql": map[string]any{"body": map[string]any{"data": map[string]any{}}},
+	}
+
+	// No server is running, mocked requests are never sent.
+	c := client.NewClient(nil)
+	c.BaseURL, _ = url.Parse("http://127.0.0.1:0/api/v4")
+
+	queries := []string{
+		`resp := gitlab.request("GET /projects/{id}/repository/branches", {"id": "reposaur/reposaur"})`,
+
+		// Mocked mutations aren't subject to the write mode.
+		`resp := gitlab.graphql("mutation { awardEmojiAdd(input: {awardableId: \"1\", name: \"thumbsup\"}) { errors } }", {})`,
+	}
+
+	for _, query := range queries {
+		opts := []func(*rego.Rego){
+			rego.Query(query),
+			rego.StrictBuiltinErrors(true),
+		}
+
+		for _, b := range gitlab.NewProvider(c).Builtins() {
+			opts = append(opts, rego.FunctionDyn(b.Func(), b.Impl))
+		}
+
+		ctx := provider.NewWriteModeContext(context.Background(), provider.WriteDenied)
+
+		rs, err := rego.New(opts...).Eval(provider.NewMockContext(ctx, mocks))
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+
+		resp := rs[0].Bindings["resp"].(map[string]any)
+
+		if status := resp["status"]; status != json.Number("200") {
+			t.Fatalf("%s: expected status 200 got %v", query, status)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package builtin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+	"github.com/reposaur/reposaur/provider"
+	"github.com/reposaur/reposaur/provider/gitlab/client"
+)
+
+type GraphQL struct {
+	Client *client.Client
+}
+
+func (gql GraphQL) Func() *rego.Function {
+	return &rego.Function{
+		Name: "gitlab.graphql",
+		Decl: types.NewFunction(
+			types.Args(
+				types.S,
+				types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)),
+			),
+			types.A,
+		),
+		Memoize: true,
+	}
+}
+
+func (gql GraphQL) Impl(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+	req, query, body, err := gql.argsToRequest(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	do := gql.Client.Do
+
+	// Mutations modify data, so they're subject to the write mode
+	// and aren't retried, unless they're mocked and so never sent.
+	if _, mocked := provider.MocksFromContext(bctx.Context); provider.IsGraphQLMutation(query) && !mocked {
+		allowed, err := provider.CheckWrite(bctx.Context, req.Method, req.URL.String(), body)
+		if err != nil {
+			return nil, err
+		}
+
+		if !allowed {
+			return ast.NewTerm(ast.MustInterfaceToValue(response{})), nil
+		}
+
+		do = gql.Client.DoOnce
+	}
+
+	finalResp, err := send(bctx.Context, do, req, "POST /graphql")
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := ast.InterfaceToValue(finalResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewTerm(val), nil
+}
+
+// argsToRequest returns the request for the query in terms,
+// along with the query and the request body.
+func (gql GraphQL) argsToRequest(terms []*ast.Term) (*retryablehttp.Request, string, []byte, error) {
+	// FIXME: Function receives 2 arguments but terms includes one additional at last index
+	if len(terms) != 3 {
+		return nil, "", nil, fmt.Errorf("wrong number of arguments, expected 2 got %d", len(terms)-1)
+	}
+
+	var (
+		query string
+		vars  map[string]any
+	)
+
+	if err := ast.As(terms[0].Value, &query); err != nil {
+		return nil, "", nil, err
+	}
+
+	if err := ast.As(terms[1].Value, &vars); err != nil {
+		return nil, "", nil, err
+	}
+
+	body := map[string]any{
+		"query":     query,
+		"variables": vars,
+	}
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(body); err != nil {
+		return nil, "", nil, err
+	}
+
+	b := buf.Bytes()
+	req, err := gql.Client.NewGraphQLRequest(bytes.NewReader(b))
+
+	return req, query, b, err
+}
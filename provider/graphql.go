@@ -0,0 +1,99 @@
+package provider
+
+// IsGraphQLMutation reports whether the GraphQL document in query
+// defines a mutation operation. The document is only tokenized, so
+// invalid documents aren't reported.
+func IsGraphQLMutation(query string) bool {
+	var (
+		depth = 0
+
+		// definition is true where a top-level definition starts,
+		// i.e. where an operation type can be.
+		definition = true
+	)
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+
+		case c == '"':
+			i = skipGraphQLString(query, i)
+
+		case c == '{' || c == '(' || c == '[':
+			// Operations without a type, e.g. { viewer { login } },
+			// are queries.
+			if depth == 0 {
+				definition = false
+			}
+
+			depth++
+			i++
+
+		case c == '}' || c == ')' || c == ']':
+			depth--
+			i++
+
+			if depth == 0 && c == '}' {
+				definition = true
+			}
+
+		case isGraphQLNameStart(c):
+			start := i
+			for i < len(query) && (isGraphQLNameStart(query[i]) || (query[i] >= '0' && query[i] <= '9')) {
+				i++
+			}
+
+			if depth == 0 && definition {
+				if query[start:i] == "mutation" {
+					return true
+				}
+
+				definition = false
+			}
+
+		default:
+			i++
+		}
+	}
+
+	return false
+}
+
+// skipGraphQLString returns the index after the string or
+// block string starting at i.
+func skipGraphQLString(query string, i int) int {
+	if len(query) >= i+3 && query[i:i+3] == `"""` {
+		for i += 3; i < len(query); i++ {
+			if query[i] == '\\' {
+				i++
+				continue
+			}
+
+			if len(query) >= i+3 && query[i:i+3] == `"""` {
+				return i + 3
+			}
+		}
+
+		return i
+	}
+
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '"', '\n':
+			return i + 1
+		}
+	}
+
+	return i
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,26 @@
+package provider
+
+import "testing"
+
+func TestIsGraphQLMutation(t *testing.T) {
+	tests := map[string]bool{
+		`{ viewer { login } }`:                false,
+		`query { viewer { login } }`:          false,
+		`query mutation { viewer { login } }`: false,
+		`query($q: String = "mutation {") { search(query: $q) { issueCount } }`: false,
+		`# mutation { }
+		query { viewer { login } }`: false,
+		`fragment f on Repository { mutation: name } query { viewer { login } }`:                 false,
+		`mutation { addStar(input: {starrableId: "1"}) { clientMutationId } }`:                   true,
+		`mutation AddStar($id: ID!) { addStar(input: {starrableId: $id}) { clientMutationId } }`: true,
+		`query { viewer { login } }
+		mutation { addStar(input: {starrableId: "1"}) { clientMutationId } }`: true,
+		`"""description""" mutation { x }`: true,
+	}
+
+	for query, expected := range tests {
+		if got := IsGraphQLMutation(query); got != expected {
+			t.Errorf("%s: expected %v got %v", query, expected, got)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MocksPath is the path of the document with the responses returned
+// by builtins while running policy tests.
+const MocksPath = "data.reposaur.mocks"
+
+// Mocks maps requests to fake responses. Keys are in the form
+// "METHOD /path", where path is either the concrete path of a request
+// (e.g. "GET /repos/reposaur/reposaur") or the path template passed to
+// a builtin (e.g. "GET /repos/{owner}/{repo}"). Concrete paths take
+// precedence. GraphQL queries are mocked by "POST /graphql".
+//
+// Values are objects with the status and body of the response. If
+// the status is omitted, defaults to 200.
+//
+// Builtins only see the mocks carried by the context of an evaluation,
+// see NewMockContext, not the data replaced by the with keyword.
+type Mocks map[string]any
+
+// MockResponse is a fake response.
+type MockResponse struct {
+	StatusCode int
+	Body       any
+}
+
+type mocksKey struct{}
+
+// NewMockContext returns a context carrying mocks. Builtins that support
+// mocking never send requests when called with this context.
+func NewMockContext(ctx context.Context, mocks Mocks) context.Context {
+	return context.WithValue(ctx, mocksKey{}, mocks)
+}
+
+// MocksFromContext returns the mocks carried by ctx, if any.
+func MocksFromContext(ctx context.Context) (Mocks, bool) {
+	m, ok := ctx.Value(mocksKey{}).(Mocks)
+	return m, ok
+}
+
+// MockedResponse returns the response of the first of keys that is mocked,
+// if ctx carries mocks. Mocked 403 Forbidden responses are returned as an
+// error, like those of requests sent. See CheckForbidden.
+func MockedResponse(ctx context.Context, keys ...string) (*MockResponse, bool, error) {
+	mocks, ok := MocksFromContext(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+
+	mock, err := mocks.Lookup(keys...)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if err := CheckForbidden(mock.StatusCode, mock.Body); err != nil {
+		return nil, true, err
+	}
+
+	return mock, true, nil
+}
+
+// Lookup returns the response of the first key that is mocked.
+// Returns an error if none of the keys are mocked or the mock
+// isn't a valid response.
+func (m Mocks) Lookup(keys ...string) (*MockResponse, error) {
+	for _, k := range keys {
+		v, ok := m[k]
+		if !ok {
+			continue
+		}
+
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("mock '%s' must be an object with status and body", k)
+		}
+
+		resp := &MockResponse{
+			StatusCode: 200,
+			Body:       obj["body"],
+		}
+
+		switch status := obj["status"].(type) {
+		case nil:
+		case json.Number:
+			n, err := status.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("mock '%s' status must be an integer", k)
+			}
+
+			resp.StatusCode = int(n)
+		case float64:
+			resp.StatusCode = int(status)
+		default:
+			return nil, fmt.Errorf("mock '%s' status must be an integer", k)
+		}
+
+		return resp, nil
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no mocked response")
+	}
+
+	return nil, fmt.Errorf("no mocked response for '%s'", keys[0])
+}
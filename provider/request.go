@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var pathParamRegex = regexp.MustCompile(`{[a-z_]+}`)
+
+// Request is a request described by the arguments of a request builtin,
+// e.g. github.request("GET /repos/{owner}/{repo}", {"owner": "reposaur"}).
+type Request struct {
+	Method string
+
+	// Path is the path with its parameters replaced by
+	// their URL-escaped values.
+	Path string
+
+	// Template is the path before replacing its
+	// parameters, e.g. /repos/{owner}/{repo}.
+	Template string
+
+	// Params are the parameters that aren't path parameters,
+	// sent in the query string or in the body of the request.
+	Params map[string]any
+}
+
+// ParseRequest parses the arguments of a request builtin: the method and
+// path, in the form "METHOD /path/{param}", and the parameters. Path
+// parameters are replaced by their URL-escaped values, so that values
+// with slashes (e.g. GitLab project paths) are sent as a single segment.
+func ParseRequest(path string, params map[string]any) (*Request, error) {
+	parts := strings.Split(path, " ")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("wrong number of parts in path, expected 2 got %d", len(parts))
+	}
+
+	r := &Request{
+		Method:   strings.ToUpper(parts[0]),
+		Path:     parts[1],
+		Template: parts[1],
+		Params:   map[string]any{},
+	}
+
+	for k, v := range params {
+		r.Params[k] = v
+	}
+
+	for _, match := range pathParamRegex.FindAllString(r.Template, -1) {
+		p := strings.TrimSuffix(strings.TrimPrefix(match, "{"), "}")
+
+		v, err := ValueToString(r.Params[p])
+		if err != nil {
+			return nil, err
+		}
+
+		r.Path = strings.Replace(r.Path, match, url.PathEscape(v), 1)
+		delete(r.Params, p)
+	}
+
+	return r, nil
+}
+
+// URL returns the path of r with its parameters in the query string.
+func (r Request) URL() (string, error) {
+	u, err := url.Parse(r.Path)
+	if err != nil {
+		return "", err
+	}
+
+	qs := url.Values{}
+
+	for k, v := range r.Params {
+		s, err := ValueToString(v)
+		if err != nil {
+			return "", err
+		}
+
+		qs.Add(k, s)
+	}
+
+	u.RawQuery = qs.Encode()
+
+	return u.String(), nil
+}
+
+// MockKeys returns the keys of the mocks matching r, i.e. its
+// concrete path and its path template. See Mocks.
+func (r Request) MockKeys() []string {
+	path, err := url.PathUnescape(r.Path)
+	if err != nil {
+		path = r.Path
+	}
+
+	return []string{r.Method + " " + path, r.Method + " " + r.Template}
+}
+
+// ValueToString returns v, a string or a number, as a string.
+func ValueToString(v any) (string, error) {
+	switch tv := v.(type) {
+	case string:
+		return tv, nil
+
+	case json.Number:
+		return tv.String(), nil
+
+	case int64:
+		return strconv.Itoa(int(tv)), nil
+	}
+
+	return "", fmt.Errorf("parse error: can't parse '%v' to string", v)
+}
+
+// DecodeBody decodes the JSON body of resp. Returns an error if resp is
+// 403 Forbidden, regardless of its body, see CheckForbidden.
+func DecodeBody(resp *http.Response) (any, error) {
+	var body any
+
+	err := json.NewDecoder(resp.Body).Decode(&body)
+
+	if err := CheckForbidden(resp.StatusCode, body); err != nil {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// CheckForbidden returns an error if status is 403 Forbidden, including
+// the message in body if it's an object with one. Bodies of any other
+// kind, e.g. HTML pages from proxies, are ignored.
+func CheckForbidden(status int, body any) error {
+	if status != http.StatusForbidden {
+		return nil
+	}
+
+	if b, ok := body.(map[string]any); ok {
+		if msg, ok := b["message"]; ok {
+			return fmt.Errorf("forbidden: %v", msg)
+		}
+	}
+
+	return fmt.Errorf("forbidden")
+}
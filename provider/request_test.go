@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		path     string
+		params   map[string]any
+		expected string
+		err      bool
+	}{
+		{
+			path:     "GET /repos/{owner}/{repo}",
+			params:   map[string]any{"owner": "reposaur", "repo": "reposaur"},
+			expected: "/repos/reposaur/reposaur",
+		},
+		{
+			path:     "get /projects/{id}/repository/branches",
+			params:   map[string]any{"id": "group/project", "per_page": json.Number("50")},
+			expected: "/projects/group%2Fproject/repository/branches?per_page=50",
+		},
+		{
+			path:     "GET /repos/{owner}/{repo}/git/ref/{ref}",
+			params:   map[string]any{"owner": "o", "repo": "r", "ref": "a b?c"},
+			expected: "/repos/o/r/git/ref/a%20b%3Fc",
+		},
+		{path: "/repos", err: true},
+		{path: "GET /repos/{owner}", params: map[string]any{}, err: true},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRequest(tt.path, tt.params)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected error", tt.path)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+
+		if r.Method != http.MethodGet {
+			t.Errorf("%s: expected method GET got %s", tt.path, r.Method)
+		}
+
+		u, err := r.URL()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+
+		if u != tt.expected {
+			t.Errorf("%s: expected %s got %s", tt.path, tt.expected, u)
+		}
+	}
+}
+
+func TestParseRequestParams(t *testing.T) {
+	params := map[string]any{"owner": "reposaur", "title": "Bug"}
+
+	r, err := ParseRequest("POST /repos/{owner}/issues", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := map[string]any{"title": "Bug"}; !reflect.DeepEqual(r.Params, expected) {
+		t.Errorf("expected params %v got %v", expected, r.Params)
+	}
+
+	if r.Template != "/repos/{owner}/issues" {
+		t.Errorf("unexpected template %s", r.Template)
+	}
+
+	// The parameters given are left untouched.
+	if len(params) != 2 {
+		t.Errorf("expected params to be unchanged got %v", params)
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		{status: http.StatusOK, body: `{"name": "reposaur"}`},
+		{status: http.StatusOK, body: `<html>`, expected: "invalid character"},
+		{status: http.StatusForbidden, body: `{"message": "rate limited"}`, expected: "forbidden: rate limited"},
+		{status: http.StatusForbidden, body: `["denied"]`, expected: "forbidden"},
+		{status: http.StatusForbidden, body: `<html>Access denied</html>`, expected: "forbidden"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(tt.status)
+		rec.WriteString(tt.body)
+
+		_, err := DecodeBody(rec.Result())
+
+		if tt.expected == "" && err != nil {
+			t.Errorf("%s: %v", tt.body, err)
+		}
+
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Errorf("%s: expected error '%s' got %v", tt.body, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// WriteMode controls whether builtins may send requests that
+// modify data (e.g. POST, PUT, PATCH or DELETE).
+type WriteMode int
+
+const (
+	// WriteDenied rejects requests that modify data. This is the default.
+	WriteDenied WriteMode = iota
+
+	// WriteDryRun logs requests that modify data instead of sending them.
+	WriteDryRun
+
+	// WriteAllowed sends requests that modify data.
+	WriteAllowed
+)
+
+type writeModeKey struct{}
+
+// NewWriteModeContext returns a context carrying mode.
+func NewWriteModeContext(ctx context.Context, mode WriteMode) context.Context {
+	return context.WithValue(ctx, writeModeKey{}, mode)
+}
+
+// WriteModeFromContext returns the write mode carried by ctx. Returns
+// WriteDenied if ctx doesn't carry one.
+func WriteModeFromContext(ctx context.Context) WriteMode {
+	mode, _ := ctx.Value(writeModeKey{}).(WriteMode)
+	return mode
+}
+
+// CheckWrite applies the write mode carried by ctx to a request that
+// modifies data, described by method, url and its JSON body, if any.
+// Returns true if the request can be sent. Returns false if it's a dry
+// run, logging the request instead, and an error if writes are denied.
+func CheckWrite(ctx context.Context, method, url string, body []byte) (bool, error) {
+	switch WriteModeFromContext(ctx) {
+	case WriteAllowed:
+		return true, nil
+
+	case WriteDryRun:
+		if body == nil {
+			body = []byte("null")
+		}
+
+		zerolog.Ctx(ctx).Info().
+			Str("method", method).
+			Str("url", url).
+			RawJSON("body", body).
+			Msg("dry run, not sending request")
+
+		return false, nil
+	}
+
+	return false, fmt.Errorf("%s requests require write access", method)
+}